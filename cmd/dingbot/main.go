@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
@@ -12,6 +13,14 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "score" {
+		if err := runScore(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "score:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	configPath := flag.String("config", "config.yaml", "config file path")
 	flag.Parse()
 
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"realtime-message/internal/auth"
+	"realtime-message/internal/calendar"
+	"realtime-message/internal/config"
+	"realtime-message/internal/core"
+	"realtime-message/internal/corroborate"
+	"realtime-message/internal/dedupe"
+	"realtime-message/internal/fetcher"
+	"realtime-message/internal/logging"
+	"realtime-message/internal/model"
+	"realtime-message/internal/push"
+	"realtime-message/internal/route"
+	"realtime-message/internal/scoring"
+)
+
+// runScore implements `dingbot score`: fetch (or read) one source, score every
+// message and print how it would be handled. Redis is only read, nothing is
+// pushed.
+func runScore(args []string) error {
+	fs := flag.NewFlagSet("score", flag.ExitOnError)
+	configPath := fs.String("config", "config.yaml", "config file path")
+	sourceName := fs.String("source", "", "source name (defaults to the only/first source)")
+	file := fs.String("file", "", "read the payload from this file instead of fetching")
+	useRedis := fs.Bool("redis", true, "check dedupe and corroboration state in redis (read-only)")
+	_ = fs.Parse(args)
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		return err
+	}
+	if cfg.Runtime.Timezone != "" {
+		loc, err := time.LoadLocation(cfg.Runtime.Timezone)
+		if err != nil {
+			return fmt.Errorf("invalid timezone: %w", err)
+		}
+		time.Local = loc
+	}
+	src, err := findSource(cfg, *sourceName)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var body []byte
+	if *file != "" {
+		body, err = os.ReadFile(*file)
+		if err == nil {
+			body, _, err = fetcher.Decode(body, "", src.Charset)
+		}
+	} else {
+		auth.SetProfiles(cfg.AuthProfiles)
+		var resp *fetcher.Response
+		resp, err = core.Fetch(ctx, src, cfg.Network, nil, nil)
+		if resp != nil {
+			body = resp.Body
+		}
+	}
+	if err != nil {
+		return err
+	}
+	msgs, err := core.Parse(src, body)
+	if err != nil {
+		return err
+	}
+
+	scores := map[string]int{}
+	for _, s := range cfg.Sources {
+		scores[s.Name] = s.BaseScore
+	}
+	scoring.SetBaseScores(scores)
+	cal, err := calendar.Load(cfg.Calendar)
+	if err != nil {
+		return err
+	}
+	engine, err := scoring.NewEngine(cfg.Topics, cfg.Triggers, cfg.Scoring, cal)
+	if err != nil {
+		return err
+	}
+	channels := cfg.PushChannels()
+	pushers, err := push.NewAll(channels)
+	if err != nil {
+		return err
+	}
+	router := route.New(channels, cfg.Routes, cfg.Push.Queue, pushers, logging.New(false))
+	defer router.Stop()
+
+	var store *dedupe.Store
+	var corr *corroborate.Tracker
+	if *useRedis {
+		store = dedupe.New(cfg.Redis, cfg.Dedupe)
+		if cfg.Corroboration.Enabled {
+			corr = corroborate.New(cfg.Redis, cfg.Corroboration)
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tSCORE\tDECISION\tBREAKDOWN\tTIME\tTITLE")
+	for i, m := range msgs {
+		scored, components := engine.Explain(m)
+		if corr != nil && !scoring.Blocked(scored) {
+			if n, err := corr.Count(ctx, m); err == nil {
+				before := scored.Score
+				scored = corr.Apply(scored, n)
+				if scored.Score != before {
+					components = append(components, scoring.Component{Name: fmt.Sprintf("corroborated:%d", n), Points: scored.Score - before})
+				}
+			}
+		}
+		decision := decide(ctx, cfg, router, store, scored)
+		when := m.Time.Format("01-02 15:04")
+		if m.TimeUnknown {
+			when = "?"
+		}
+		fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%s\t%s\n", i+1, scored.Score, decision, breakdown(components), when, shorten(firstNonEmpty(m.Title, m.Content), 40))
+	}
+	return tw.Flush()
+}
+
+func findSource(cfg config.Config, name string) (config.SourceConfig, error) {
+	if name == "" {
+		return cfg.Sources[0], nil
+	}
+	for _, s := range cfg.Sources {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return config.SourceConfig{}, fmt.Errorf("source %q not found", name)
+}
+
+func decide(ctx context.Context, cfg config.Config, router *route.Router, store *dedupe.Store, scored model.ScoredMessage) string {
+	if scoring.Blocked(scored) {
+		return "drop"
+	}
+	targets := router.Route(scored)
+	if len(targets) == 0 {
+		if cfg.Digest.Enabled && scored.Score < cfg.Scoring.PushThreshold && scored.Score >= cfg.Scoring.DigestThreshold {
+			return "digest"
+		}
+		return "drop"
+	}
+	parts := make([]string, 0, len(targets))
+	for _, t := range targets {
+		name := t.Pusher.Name()
+		if store == nil {
+			parts = append(parts, "push:"+name)
+			continue
+		}
+		seen, _, err := store.Check(ctx, name, scored.Message)
+		switch {
+		case err != nil:
+			parts = append(parts, "push?:"+name)
+		case seen:
+			parts = append(parts, "dup:"+name)
+		default:
+			parts = append(parts, "push:"+name)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func breakdown(components []scoring.Component) string {
+	parts := make([]string, 0, len(components))
+	for _, c := range components {
+		parts = append(parts, fmt.Sprintf("%s=%+d", c.Name, c.Points))
+	}
+	return strings.Join(parts, " ")
+}
+
+func shorten(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max]) + "…"
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,289 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"realtime-message/internal/config"
+	"realtime-message/internal/parser"
+)
+
+// refreshEarly renews cached tokens a little before they expire.
+const refreshEarly = 30 * time.Second
+
+var (
+	mu       sync.Mutex
+	profiles = map[string]*Profile{}
+)
+
+// SetProfiles installs the auth profiles of a config. Profiles whose config
+// is unchanged keep their cached token and cookies across reloads.
+func SetProfiles(cfgs []config.AuthConfig) {
+	mu.Lock()
+	defer mu.Unlock()
+	next := make(map[string]*Profile, len(cfgs))
+	for _, c := range cfgs {
+		if p, ok := profiles[c.Name]; ok && reflect.DeepEqual(p.cfg, c) {
+			next[c.Name] = p
+			continue
+		}
+		next[c.Name] = &Profile{cfg: c, client: &http.Client{Timeout: 10 * time.Second}, cookies: map[string]*http.Cookie{}}
+	}
+	profiles = next
+}
+
+func Lookup(name string) *Profile {
+	mu.Lock()
+	defer mu.Unlock()
+	return profiles[name]
+}
+
+// Profile holds the credentials and session state shared by every source
+// that names it.
+type Profile struct {
+	cfg    config.AuthConfig
+	client *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+	cookies map[string]*http.Cookie
+	loaded  bool
+}
+
+func (p *Profile) Name() string {
+	return p.cfg.Name
+}
+
+// Apply adds credentials to req, logging in first when the profile has no
+// valid token or session.
+func (p *Profile) Apply(ctx context.Context, req *http.Request) error {
+	switch strings.ToLower(p.cfg.Type) {
+	case "basic":
+		req.SetBasicAuth(p.cfg.Username, p.cfg.Password)
+	case "bearer":
+		token := p.cfg.Token
+		if p.cfg.TokenFile != "" {
+			raw, err := os.ReadFile(p.cfg.TokenFile)
+			if err != nil {
+				return fmt.Errorf("auth %s: %w", p.cfg.Name, err)
+			}
+			token = strings.TrimSpace(string(raw))
+		}
+		p.setHeader(req, token)
+	case "token":
+		token, err := p.loginToken(ctx)
+		if err != nil {
+			return err
+		}
+		p.setHeader(req, token)
+	case "cookie":
+		cookies, err := p.session(ctx)
+		if err != nil {
+			return err
+		}
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+	}
+	return nil
+}
+
+// Invalidate drops the cached token or session so the next Apply logs in
+// again, e.g. after a 401.
+func (p *Profile) Invalidate() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.token = ""
+	p.expires = time.Time{}
+	if p.cfg.Login.URL != "" {
+		p.cookies = map[string]*http.Cookie{}
+		p.persist()
+	}
+}
+
+// Observe keeps cookies that a source response sets on cookie profiles. The
+// saved session is loaded first so that persisting does not drop it.
+func (p *Profile) Observe(h http.Header) {
+	if strings.ToLower(p.cfg.Type) != "cookie" || len(h.Values("Set-Cookie")) == 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ensureLoaded()
+	p.merge((&http.Response{Header: h}).Cookies())
+	p.persist()
+}
+
+func (p *Profile) setHeader(req *http.Request, token string) {
+	header := p.cfg.Header
+	if header == "" {
+		header = "Authorization"
+	}
+	prefix := "Bearer "
+	if p.cfg.Prefix != nil {
+		prefix = *p.cfg.Prefix
+	}
+	req.Header.Set(header, prefix+token)
+}
+
+func (p *Profile) loginToken(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token != "" && (p.expires.IsZero() || time.Now().Before(p.expires.Add(-refreshEarly))) {
+		return p.token, nil
+	}
+	body, _, err := p.login(ctx)
+	if err != nil {
+		return "", err
+	}
+	token := parser.JSONValue(body, p.cfg.Login.TokenPath)
+	if token == "" {
+		return "", fmt.Errorf("auth %s: no token at %q", p.cfg.Name, p.cfg.Login.TokenPath)
+	}
+	ttl := time.Duration(p.cfg.Login.TTLSeconds) * time.Second
+	if p.cfg.Login.ExpiresInPath != "" {
+		if sec, err := strconv.ParseFloat(parser.JSONValue(body, p.cfg.Login.ExpiresInPath), 64); err == nil && sec > 0 {
+			ttl = time.Duration(sec * float64(time.Second))
+		}
+	}
+	p.token = token
+	p.expires = time.Time{}
+	if ttl > 0 {
+		p.expires = time.Now().Add(ttl)
+	}
+	return token, nil
+}
+
+func (p *Profile) session(ctx context.Context) ([]*http.Cookie, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ensureLoaded()
+	now := time.Now()
+	for name, c := range p.cookies {
+		if !c.Expires.IsZero() && now.After(c.Expires) {
+			delete(p.cookies, name)
+		}
+	}
+	if len(p.cookies) == 0 && p.cfg.Login.URL != "" {
+		_, cookies, err := p.login(ctx)
+		if err != nil {
+			return nil, err
+		}
+		p.merge(cookies)
+		if len(p.cookies) == 0 {
+			return nil, fmt.Errorf("auth %s: login set no cookies", p.cfg.Name)
+		}
+		p.persist()
+	}
+	out := make([]*http.Cookie, 0, len(p.cookies))
+	for _, c := range p.cookies {
+		out = append(out, &http.Cookie{Name: c.Name, Value: c.Value})
+	}
+	return out, nil
+}
+
+// login posts the login request; ${username} and ${password} in its body
+// come from the profile, anything else from the environment.
+func (p *Profile) login(ctx context.Context) ([]byte, []*http.Cookie, error) {
+	lc := p.cfg.Login
+	method := strings.ToUpper(lc.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+	body := os.Expand(lc.Body, func(k string) string {
+		switch k {
+		case "username":
+			return p.cfg.Username
+		case "password":
+			return p.cfg.Password
+		}
+		return os.Getenv(k)
+	})
+	req, err := http.NewRequestWithContext(ctx, method, lc.URL, bytes.NewReader([]byte(body)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("auth %s: %w", p.cfg.Name, err)
+	}
+	for k, v := range lc.Headers {
+		req.Header.Set(k, os.ExpandEnv(v))
+	}
+	if body != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("auth %s login: %w", p.cfg.Name, err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("auth %s login: %w", p.cfg.Name, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, nil, fmt.Errorf("auth %s login: %s", p.cfg.Name, resp.Status)
+	}
+	return raw, resp.Cookies(), nil
+}
+
+func (p *Profile) merge(cookies []*http.Cookie) {
+	now := time.Now()
+	for _, c := range cookies {
+		switch {
+		case c.MaxAge < 0:
+			delete(p.cookies, c.Name)
+			continue
+		case c.MaxAge > 0:
+			c.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		}
+		p.cookies[c.Name] = &http.Cookie{Name: c.Name, Value: c.Value, Expires: c.Expires}
+	}
+}
+
+// ensureLoaded reads the cookie file once. The caller holds p.mu.
+func (p *Profile) ensureLoaded() {
+	if !p.loaded {
+		p.loaded = true
+		p.load()
+	}
+}
+
+func (p *Profile) load() {
+	if p.cfg.CookieFile == "" {
+		return
+	}
+	raw, err := os.ReadFile(p.cfg.CookieFile)
+	if err != nil {
+		return
+	}
+	var cookies []*http.Cookie
+	if json.Unmarshal(raw, &cookies) == nil {
+		for _, c := range cookies {
+			p.cookies[c.Name] = c
+		}
+	}
+}
+
+func (p *Profile) persist() {
+	if p.cfg.CookieFile == "" {
+		return
+	}
+	cookies := make([]*http.Cookie, 0, len(p.cookies))
+	for _, c := range p.cookies {
+		cookies = append(cookies, c)
+	}
+	raw, err := json.Marshal(cookies)
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(p.cfg.CookieFile, raw, 0o600)
+}
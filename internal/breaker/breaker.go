@@ -0,0 +1,180 @@
+package breaker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"realtime-message/internal/config"
+	"realtime-message/internal/logging"
+	"realtime-message/internal/metrics"
+	"realtime-message/internal/model"
+	"realtime-message/internal/push"
+)
+
+type State int
+
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// Breaker guards one source: it opens after threshold consecutive failures,
+// lets a single probe through once the cooldown has passed, and doubles the
+// cooldown (up to maxCooldown) every time the probe fails.
+type Breaker struct {
+	source       string
+	threshold    int
+	baseCooldown time.Duration
+	maxCooldown  time.Duration
+	alertAfter   time.Duration
+	alert        push.Pusher
+	logger       *logging.Logger
+
+	mu        sync.Mutex
+	state     State
+	failures  int
+	cooldown  time.Duration
+	openedAt  time.Time
+	downSince time.Time
+	alerted   bool
+}
+
+func New(source string, cfg config.BreakerConfig, alert push.Pusher, logger *logging.Logger) *Breaker {
+	b := &Breaker{source: source, logger: logger}
+	b.configure(cfg, alert)
+	b.cooldown = b.baseCooldown
+	metrics.Set(metrics.Name("breaker_state", source), int64(Closed))
+	return b
+}
+
+// Reconfigure applies a reloaded config while keeping the current state,
+// failure count and cooldown, so reloads do not reset a failing source.
+func (b *Breaker) Reconfigure(cfg config.BreakerConfig, alert push.Pusher) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.configure(cfg, alert)
+	if b.cooldown < b.baseCooldown {
+		b.cooldown = b.baseCooldown
+	}
+	if b.cooldown > b.maxCooldown {
+		b.cooldown = b.maxCooldown
+	}
+}
+
+func (b *Breaker) configure(cfg config.BreakerConfig, alert push.Pusher) {
+	threshold := cfg.FailureThreshold
+	if threshold <= 0 {
+		threshold = 5
+	}
+	base := time.Duration(cfg.CooldownSeconds) * time.Second
+	if base <= 0 {
+		base = time.Minute
+	}
+	maxCooldown := time.Duration(cfg.MaxCooldownSeconds) * time.Second
+	if maxCooldown < base {
+		maxCooldown = 30 * time.Minute
+		if maxCooldown < base {
+			maxCooldown = base
+		}
+	}
+	b.threshold = threshold
+	b.baseCooldown = base
+	b.maxCooldown = maxCooldown
+	b.alertAfter = time.Duration(cfg.AlertAfterSeconds) * time.Second
+	b.alert = alert
+}
+
+// Allow reports whether a fetch may run now. An open breaker turns half-open
+// once its cooldown has elapsed and admits that one call as the probe.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case Open:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.transition(HalfOpen)
+		return true
+	default:
+		return true
+	}
+}
+
+// Record feeds the outcome of an allowed call back into the breaker.
+func (b *Breaker) Record(ctx context.Context, err error) {
+	b.mu.Lock()
+	var alert string
+	if err == nil {
+		if b.state != Closed {
+			b.transition(Closed)
+		}
+		if b.alerted {
+			alert = fmt.Sprintf("【恢复】消息源 %s 已恢复，中断 %s", b.source, time.Since(b.downSince).Round(time.Second))
+		}
+		b.failures = 0
+		b.cooldown = b.baseCooldown
+		b.downSince = time.Time{}
+		b.alerted = false
+	} else {
+		b.failures++
+		if b.downSince.IsZero() {
+			b.downSince = time.Now()
+		}
+		switch {
+		case b.state == HalfOpen:
+			b.cooldown *= 2
+			if b.cooldown > b.maxCooldown {
+				b.cooldown = b.maxCooldown
+			}
+			b.openedAt = time.Now()
+			b.transition(Open)
+		case b.state == Closed && b.failures >= b.threshold:
+			b.openedAt = time.Now()
+			b.transition(Open)
+		}
+		if b.alertAfter > 0 && !b.alerted && time.Since(b.downSince) >= b.alertAfter {
+			b.alerted = true
+			alert = fmt.Sprintf("【告警】消息源 %s 已持续失败 %s（连续 %d 次）：%v", b.source, time.Since(b.downSince).Round(time.Second), b.failures, err)
+		}
+	}
+	pusher := b.alert
+	b.mu.Unlock()
+	if alert != "" {
+		b.sendAlert(ctx, pusher, alert)
+	}
+}
+
+func (b *Breaker) transition(to State) {
+	from := b.state
+	b.state = to
+	metrics.Set(metrics.Name("breaker_state", b.source), int64(to))
+	if to == Open {
+		metrics.Inc(metrics.Name("breaker_opened", b.source))
+	}
+	b.logger.Warn("breaker state", logging.Field{Key: "source", Val: b.source}, logging.Field{Key: "from", Val: from.String()}, logging.Field{Key: "to", Val: to.String()}, logging.Field{Key: "failures", Val: b.failures}, logging.Field{Key: "cooldown_s", Val: b.cooldown.Seconds()})
+}
+
+func (b *Breaker) sendAlert(ctx context.Context, alert push.Pusher, text string) {
+	if alert == nil {
+		return
+	}
+	msg := model.ScoredMessage{Message: model.Message{Title: text, Content: text, Source: b.source, Time: time.Now()}}
+	if err := alert.Send(ctx, msg, text); err != nil {
+		b.logger.Error("breaker alert failed", logging.Field{Key: "source", Val: b.source}, logging.Field{Key: "err", Val: err})
+	}
+}
@@ -0,0 +1,155 @@
+package calendar
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"realtime-message/internal/config"
+)
+
+const (
+	PreOpen       = "pre_open"
+	CallAuction   = "call_auction"
+	InSession     = "in_session"
+	LunchBreak    = "lunch_break"
+	AfterClose    = "after_close"
+	NonTradingDay = "non_trading_day"
+)
+
+var defaultSessions = []config.SessionConfig{
+	{Name: "call_auction", Start: "09:15", End: "09:25", Phase: CallAuction},
+	{Name: "morning", Start: "09:30", End: "11:30"},
+	{Name: "afternoon", Start: "13:00", End: "15:00"},
+}
+
+type session struct {
+	name  string
+	start int
+	end   int
+	phase string
+}
+
+type Calendar struct {
+	holidays        map[string]bool
+	workdays        map[string]bool
+	tradeOnWorkdays bool
+	sessions        []session
+	firstOpen       int
+	lastClose       int
+}
+
+type file struct {
+	Holidays []string `yaml:"holidays"`
+	Workdays []string `yaml:"workdays"`
+}
+
+func Load(cfg config.CalendarConfig) (*Calendar, error) {
+	c := &Calendar{
+		holidays:        map[string]bool{},
+		workdays:        map[string]bool{},
+		tradeOnWorkdays: cfg.TradeOnWorkdays,
+		firstOpen:       -1,
+	}
+	if cfg.File != "" {
+		raw, err := os.ReadFile(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		var f file
+		if err := yaml.Unmarshal(raw, &f); err != nil {
+			return nil, fmt.Errorf("calendar %s: %w", cfg.File, err)
+		}
+		for _, d := range f.Holidays {
+			if _, err := time.Parse("2006-01-02", d); err != nil {
+				return nil, fmt.Errorf("calendar %s: invalid holiday %q", cfg.File, d)
+			}
+			c.holidays[d] = true
+		}
+		for _, d := range f.Workdays {
+			if _, err := time.Parse("2006-01-02", d); err != nil {
+				return nil, fmt.Errorf("calendar %s: invalid workday %q", cfg.File, d)
+			}
+			c.workdays[d] = true
+		}
+	}
+	sessions := cfg.Sessions
+	if len(sessions) == 0 {
+		sessions = defaultSessions
+	}
+	for _, s := range sessions {
+		start, err := minuteOfDay(s.Start)
+		if err != nil {
+			return nil, fmt.Errorf("session %s: %w", s.Name, err)
+		}
+		end, err := minuteOfDay(s.End)
+		if err != nil {
+			return nil, fmt.Errorf("session %s: %w", s.Name, err)
+		}
+		if end < start {
+			return nil, fmt.Errorf("session %s: end before start", s.Name)
+		}
+		phase := s.Phase
+		if phase == "" {
+			phase = InSession
+		}
+		c.sessions = append(c.sessions, session{name: s.Name, start: start, end: end, phase: phase})
+		if phase == InSession {
+			if c.firstOpen < 0 || start < c.firstOpen {
+				c.firstOpen = start
+			}
+			if end > c.lastClose {
+				c.lastClose = end
+			}
+		}
+	}
+	if c.firstOpen < 0 {
+		return nil, fmt.Errorf("calendar needs at least one in_session session")
+	}
+	return c, nil
+}
+
+func (c *Calendar) IsTradingDay(t time.Time) bool {
+	lt := t.In(time.Local)
+	day := lt.Format("2006-01-02")
+	if c.holidays[day] {
+		return false
+	}
+	if lt.Weekday() == time.Saturday || lt.Weekday() == time.Sunday {
+		return c.tradeOnWorkdays && c.workdays[day]
+	}
+	return true
+}
+
+// Phase classifies t into one of the market phases. Times between the first
+// and last in_session windows that fall in no window are the lunch break.
+func (c *Calendar) Phase(t time.Time) string {
+	if !c.IsTradingDay(t) {
+		return NonTradingDay
+	}
+	lt := t.In(time.Local)
+	m := lt.Hour()*60 + lt.Minute()
+	for _, s := range c.sessions {
+		if m >= s.start && m <= s.end {
+			return s.phase
+		}
+	}
+	switch {
+	case m < c.firstOpen:
+		return PreOpen
+	case m > c.lastClose:
+		return AfterClose
+	default:
+		return LunchBreak
+	}
+}
+
+func minuteOfDay(hm string) (int, error) {
+	t, err := time.Parse("15:04", hm)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", hm)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,57 @@
+package calendar
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"realtime-message/internal/config"
+)
+
+func TestPhase(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "calendar.yaml")
+	if err := os.WriteFile(file, []byte("holidays: [\"2026-10-01\"]\nworkdays: [\"2026-10-10\"]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(config.CalendarConfig{File: file, TradeOnWorkdays: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := func(d, h, m int) time.Time { return time.Date(2026, 10, d, h, m, 0, 0, time.Local) }
+	cases := []struct {
+		t    time.Time
+		want string
+	}{
+		{day(16, 9, 0), PreOpen},
+		{day(16, 9, 20), CallAuction},
+		{day(16, 9, 27), PreOpen},
+		{day(16, 9, 30), InSession},
+		{day(16, 11, 30), InSession},
+		{day(16, 12, 0), LunchBreak},
+		{day(16, 14, 59), InSession},
+		{day(16, 15, 30), AfterClose},
+		{day(17, 10, 0), NonTradingDay},
+		{day(1, 10, 0), NonTradingDay},
+		{day(10, 10, 0), InSession},
+	}
+	for _, c2 := range cases {
+		if got := c.Phase(c2.t); got != c2.want {
+			t.Errorf("%v: got %s, want %s", c2.t, got, c2.want)
+		}
+	}
+}
+
+func TestPhaseWorkdaysNotTraded(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "calendar.yaml")
+	if err := os.WriteFile(file, []byte("workdays: [\"2026-10-10\"]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(config.CalendarConfig{File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Phase(time.Date(2026, 10, 10, 10, 0, 0, 0, time.Local)); got != NonTradingDay {
+		t.Errorf("got %s, want %s", got, NonTradingDay)
+	}
+}
@@ -4,42 +4,57 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"regexp"
 	"strings"
+	"time"
 
+	"golang.org/x/net/html/charset"
 	"gopkg.in/yaml.v3"
+
+	"realtime-message/internal/cron"
 )
 
 type Config struct {
-	Runtime  RuntimeConfig  `yaml:"runtime"`
-	Network  NetworkConfig  `yaml:"network"`
-	Redis    RedisConfig    `yaml:"redis"`
-	Dingding DingdingConfig `yaml:"dingding"`
-	Scoring  ScoringConfig  `yaml:"scoring"`
-	Sources  []SourceConfig `yaml:"sources"`
-	Topics   []TopicConfig  `yaml:"topics"`
-	Triggers TriggerConfig  `yaml:"triggers"`
-	Push     PushConfig     `yaml:"push"`
-	Dedupe   DedupeConfig   `yaml:"dedupe"`
-	Logging  LoggingConfig  `yaml:"logging"`
+	Runtime       RuntimeConfig       `yaml:"runtime"`
+	Network       NetworkConfig       `yaml:"network"`
+	Redis         RedisConfig         `yaml:"redis"`
+	Dingding      DingdingConfig      `yaml:"dingding"`
+	Channels      []ChannelConfig     `yaml:"channels"`
+	Routes        []RouteConfig       `yaml:"routes"`
+	Scoring       ScoringConfig       `yaml:"scoring"`
+	Calendar      CalendarConfig      `yaml:"calendar"`
+	Sources       []SourceConfig      `yaml:"sources"`
+	Topics        []TopicConfig       `yaml:"topics"`
+	Triggers      TriggerConfig       `yaml:"triggers"`
+	Push          PushConfig          `yaml:"push"`
+	Dedupe        DedupeConfig        `yaml:"dedupe"`
+	Digest        DigestConfig        `yaml:"digest"`
+	Corroboration CorroborationConfig `yaml:"corroboration"`
+	Breaker       BreakerConfig       `yaml:"breaker"`
+	AuthProfiles  []AuthConfig        `yaml:"auth_profiles"`
+	Sink          SinkConfig          `yaml:"sink"`
+	Logging       LoggingConfig       `yaml:"logging"`
 }
 
 type RuntimeConfig struct {
 	Timezone                   string `yaml:"timezone"`
 	DefaultPollIntervalSeconds int    `yaml:"default_poll_interval_seconds"`
 	ReloadIntervalSeconds      int    `yaml:"reload_interval_seconds"`
+	MetricsListen              string `yaml:"metrics_listen"`
+	IngestListen               string `yaml:"ingest_listen"`
 }
 
 type NetworkConfig struct {
-	DefaultTimeoutMS int        `yaml:"default_timeout_ms"`
+	DefaultTimeoutMS int         `yaml:"default_timeout_ms"`
 	Retry            RetryConfig `yaml:"retry"`
 }
 
 type RetryConfig struct {
-	MaxAttempts  int     `yaml:"max_attempts"`
-	BackoffMS    int     `yaml:"backoff_ms"`
-	Multiplier   float64 `yaml:"multiplier"`
-	JitterMS     int     `yaml:"jitter_ms"`
-	RetryOnStatus []int  `yaml:"retry_on_status"`
+	MaxAttempts   int     `yaml:"max_attempts"`
+	BackoffMS     int     `yaml:"backoff_ms"`
+	Multiplier    float64 `yaml:"multiplier"`
+	JitterMS      int     `yaml:"jitter_ms"`
+	RetryOnStatus []int   `yaml:"retry_on_status"`
 }
 
 type RedisConfig struct {
@@ -50,50 +65,218 @@ type RedisConfig struct {
 }
 
 type DingdingConfig struct {
-	Webhook  string `yaml:"webhook"`
-	Secret   string `yaml:"secret"`
-	MsgType  string `yaml:"msg_type"`
-	Title    string `yaml:"title"`
-	TimeoutMS int   `yaml:"timeout_ms"`
+	Webhook   string `yaml:"webhook"`
+	Secret    string `yaml:"secret"`
+	MsgType   string `yaml:"msg_type"`
+	Title     string `yaml:"title"`
+	TimeoutMS int    `yaml:"timeout_ms"`
+}
+
+type ChannelConfig struct {
+	Name      string            `yaml:"name"`
+	Type      string            `yaml:"type"`
+	Webhook   string            `yaml:"webhook"`
+	Secret    string            `yaml:"secret"`
+	MsgType   string            `yaml:"msg_type"`
+	Title     string            `yaml:"title"`
+	TimeoutMS int               `yaml:"timeout_ms"`
+	Template  string            `yaml:"template"`
+	Headers   map[string]string `yaml:"headers"`
+
+	PushThreshold    int `yaml:"push_threshold"`
+	MaxPushPerMinute int `yaml:"max_push_per_minute"`
+}
+
+type RouteConfig struct {
+	Name     string   `yaml:"name"`
+	Topics   []string `yaml:"topics"`
+	Sources  []string `yaml:"sources"`
+	Keywords []string `yaml:"keywords"`
+	MinScore int      `yaml:"min_score"`
+	MaxScore int      `yaml:"max_score"`
+	Channels []string `yaml:"channels"`
 }
 
 type ScoringConfig struct {
-	PushThreshold int           `yaml:"push_threshold"`
-	MarketHours   MarketHoursConfig `yaml:"market_hours"`
+	PushThreshold   int               `yaml:"push_threshold"`
+	DigestThreshold int               `yaml:"digest_threshold"`
+	Blacklist       BlacklistConfig   `yaml:"blacklist"`
+	MarketHours     MarketHoursConfig `yaml:"market_hours"`
 }
 
 type MarketHoursConfig struct {
-	Enabled          bool `yaml:"enabled"`
-	InSessionBonus   int  `yaml:"in_session_bonus"`
-	OffSessionPenalty int `yaml:"off_session_penalty"`
+	Enabled           bool           `yaml:"enabled"`
+	InSessionBonus    int            `yaml:"in_session_bonus"`
+	OffSessionPenalty int            `yaml:"off_session_penalty"`
+	Phases            map[string]int `yaml:"phases"`
+}
+
+type CalendarConfig struct {
+	File            string          `yaml:"file"`
+	TradeOnWorkdays bool            `yaml:"trade_on_workdays"`
+	Sessions        []SessionConfig `yaml:"sessions"`
+}
+
+type SessionConfig struct {
+	Name  string `yaml:"name"`
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+	Phase string `yaml:"phase"`
 }
 
 type SourceConfig struct {
-	Name                 string        `yaml:"name"`
-	Type                 string        `yaml:"type"`
-	URL                  string        `yaml:"url"`
-	PollIntervalSeconds  int           `yaml:"poll_interval_seconds"`
-	TimeoutMS            int           `yaml:"timeout_ms"`
-	Retry                RetryConfig    `yaml:"retry"`
-	BaseScore            int           `yaml:"base_score"`
-	Headers              map[string]string `yaml:"headers"`
-	Parser               ParserConfig   `yaml:"parser"`
+	Name                string            `yaml:"name"`
+	Type                string            `yaml:"type"`
+	URL                 string            `yaml:"url"`
+	Method              string            `yaml:"method"`
+	Query               map[string]string `yaml:"query"`
+	Body                string            `yaml:"body"`
+	PollIntervalSeconds int               `yaml:"poll_interval_seconds"`
+	TimeoutMS           int               `yaml:"timeout_ms"`
+	Retry               RetryConfig       `yaml:"retry"`
+	BaseScore           int               `yaml:"base_score"`
+	Headers             map[string]string `yaml:"headers"`
+	Parser              ParserConfig      `yaml:"parser"`
+	Charset             string            `yaml:"charset"`
+	Incremental         bool              `yaml:"incremental"`
+	LookbackSeconds     int               `yaml:"lookback_seconds"`
+	Polling             PollingConfig     `yaml:"polling"`
+	Schedule            []ScheduleConfig  `yaml:"schedule"`
+	Auth                string            `yaml:"auth"`
+	Ingest              IngestConfig      `yaml:"ingest"`
+	Stream              StreamConfig      `yaml:"stream"`
+	RedisStream         RedisStreamConfig `yaml:"redis_stream"`
+}
+
+// RedisStreamConfig reads a Redis stream through a consumer group. Field
+// holds the JSON payload; without it the entry's fields are the message.
+type RedisStreamConfig struct {
+	Stream   string `yaml:"stream"`
+	Group    string `yaml:"group"`
+	Consumer string `yaml:"consumer"`
+	Field    string `yaml:"field"`
+	Count    int    `yaml:"count"`
+	BlockMS  int    `yaml:"block_ms"`
+}
+
+// StreamConfig tunes sse and websocket sources. Subscribe is sent as a text
+// frame after a websocket connects; Events limits which SSE event types are
+// parsed (empty means all).
+type StreamConfig struct {
+	Subscribe          string   `yaml:"subscribe"`
+	Events             []string `yaml:"events"`
+	IdleTimeoutSeconds int      `yaml:"idle_timeout_seconds"`
+	MaxBackoffSeconds  int      `yaml:"max_backoff_seconds"`
+}
+
+// IngestConfig authenticates pushes to /ingest/{source} for sources of type
+// "ingest": a bearer Token, an HMAC-SHA256 Secret over the body, or both.
+type IngestConfig struct {
+	Token           string `yaml:"token"`
+	Secret          string `yaml:"secret"`
+	SignatureHeader string `yaml:"signature_header"`
+}
+
+// ScheduleConfig is one cron entry; a bare string is accepted as the cron
+// expression.
+type ScheduleConfig struct {
+	Cron        string `yaml:"cron"`
+	TradingDays bool   `yaml:"trading_days"`
+}
+
+func (s *ScheduleConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		s.Cron = node.Value
+		return nil
+	}
+	type plain ScheduleConfig
+	return node.Decode((*plain)(s))
+}
+
+// PollingConfig overrides poll_interval_seconds per market phase and, when
+// adaptive, shortens or stretches the interval based on recent fetches.
+type PollingConfig struct {
+	Intervals  map[string]int `yaml:"intervals"`
+	Adaptive   bool           `yaml:"adaptive"`
+	MinSeconds int            `yaml:"min_seconds"`
+	MaxSeconds int            `yaml:"max_seconds"`
 }
 
 type ParserConfig struct {
-	Mode    string        `yaml:"mode"`
-	Mapping MappingConfig `yaml:"mapping"`
+	Mode       string           `yaml:"mode"`
+	Mapping    MappingConfig    `yaml:"mapping"`
+	HTML       HTMLConfig       `yaml:"html"`
+	Pagination PaginationConfig `yaml:"pagination"`
+	Time       TimeConfig       `yaml:"time"`
+}
+
+// TimeConfig lists Go time layouts tried before the built-in ones, and the
+// timezone of times without an offset (default runtime.timezone).
+type TimeConfig struct {
+	Layouts  []string `yaml:"layouts"`
+	Timezone string   `yaml:"timezone"`
+}
+
+// PaginationConfig walks further pages after the first. Mode is "page",
+// "offset" or "cursor"; the value goes into the Param query parameter and
+// is also available as ${page} / ${offset} / ${cursor} in templates.
+type PaginationConfig struct {
+	Mode       string `yaml:"mode"`
+	Param      string `yaml:"param"`
+	Start      int    `yaml:"start"`
+	Size       int    `yaml:"size"`
+	CursorPath string `yaml:"cursor_path"`
+	MaxPages   int    `yaml:"max_pages"`
+}
+
+type HTMLConfig struct {
+	Item   string            `yaml:"item"`
+	Fields map[string]string `yaml:"fields"`
 }
 
+// MappingConfig paths are JSONPath-like: dotted keys, [n] indices, [*] and
+// * wildcards, and [?(@.key == value)] filters.
 type MappingConfig struct {
-	ListPath string            `yaml:"list_path"`
-	Fields   map[string]string `yaml:"fields"`
+	ListPath string                 `yaml:"list_path"`
+	Fields   map[string]FieldConfig `yaml:"fields"`
+}
+
+// FieldConfig extracts one message field. A bare string is the path. The
+// transforms run in order: join arrays, strip_html, regex, prefix, default;
+// layout parses the time field.
+type FieldConfig struct {
+	Path      string `yaml:"path"`
+	Join      string `yaml:"join"`
+	StripHTML bool   `yaml:"strip_html"`
+	Regex     string `yaml:"regex"`
+	Prefix    string `yaml:"prefix"`
+	Default   string `yaml:"default"`
+	Layout    string `yaml:"layout"`
+}
+
+func (f *FieldConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		f.Path = node.Value
+		return nil
+	}
+	type plain FieldConfig
+	return node.Decode((*plain)(f))
 }
 
 type TopicConfig struct {
-	Name     string   `yaml:"name"`
-	Weight   int      `yaml:"weight"`
+	Name         string `yaml:"name"`
+	Weight       int    `yaml:"weight"`
+	KeywordRules `yaml:",inline"`
+}
+
+// KeywordRules matches when every all_of word, at least one of keywords /
+// any_of / regex (if any are given) and none of none_of occur in the text.
+type KeywordRules struct {
 	Keywords []string `yaml:"keywords"`
+	AllOf    []string `yaml:"all_of"`
+	AnyOf    []string `yaml:"any_of"`
+	NoneOf   []string `yaml:"none_of"`
+	Regex    []string `yaml:"regex"`
 }
 
 type TriggerConfig struct {
@@ -101,13 +284,25 @@ type TriggerConfig struct {
 }
 
 type StrongTriggerConfig struct {
-	Weight   int      `yaml:"weight"`
-	Keywords []string `yaml:"keywords"`
+	Weight       int `yaml:"weight"`
+	KeywordRules `yaml:",inline"`
+}
+
+type BlacklistConfig struct {
+	Words   []string `yaml:"words"`
+	Sources []string `yaml:"sources"`
 }
 
 type PushConfig struct {
-	MaxPushPerMinute int          `yaml:"max_push_per_minute"`
+	MaxPushPerMinute int            `yaml:"max_push_per_minute"`
 	Template         TemplateConfig `yaml:"template"`
+	Queue            QueueConfig    `yaml:"queue"`
+}
+
+type QueueConfig struct {
+	MaxSize       int    `yaml:"max_size"`
+	MaxAgeSeconds int    `yaml:"max_age_seconds"`
+	OnExpire      string `yaml:"on_expire"`
 }
 
 type TemplateConfig struct {
@@ -115,8 +310,73 @@ type TemplateConfig struct {
 }
 
 type DedupeConfig struct {
-	TTLHours   int      `yaml:"ttl_hours"`
-	KeyStrategy []string `yaml:"key_strategy"`
+	TTLHours          int      `yaml:"ttl_hours"`
+	KeyStrategy       []string `yaml:"key_strategy"`
+	ReserveTTLSeconds int      `yaml:"reserve_ttl_seconds"`
+	SimHashDistance   *int     `yaml:"simhash_distance"`
+	// LegacyChannel is the channel that still honors the unscoped dedupe
+	// keys written before keys were kept per channel (default "dingding").
+	LegacyChannel string `yaml:"legacy_channel"`
+}
+
+type DigestConfig struct {
+	Enabled         bool     `yaml:"enabled"`
+	Schedule        []string `yaml:"schedule"`
+	IntervalMinutes int      `yaml:"interval_minutes"`
+	MaxItems        int      `yaml:"max_items"`
+	Title           string   `yaml:"title"`
+	Channels        []string `yaml:"channels"`
+}
+
+type CorroborationConfig struct {
+	Enabled       bool    `yaml:"enabled"`
+	WindowMinutes int     `yaml:"window_minutes"`
+	Similarity    float64 `yaml:"similarity"`
+	MinSources    int     `yaml:"min_sources"`
+	Bonus         int     `yaml:"bonus"`
+	HoldSeconds   int     `yaml:"hold_seconds"`
+	HoldMargin    int     `yaml:"hold_margin"`
+}
+
+// AuthConfig is a named credential profile that sources refer to by name.
+// Type is basic, bearer, cookie or token.
+type AuthConfig struct {
+	Name       string      `yaml:"name"`
+	Type       string      `yaml:"type"`
+	Username   string      `yaml:"username"`
+	Password   string      `yaml:"password"`
+	Token      string      `yaml:"token"`
+	TokenFile  string      `yaml:"token_file"`
+	Header     string      `yaml:"header"`
+	Prefix     *string     `yaml:"prefix"`
+	CookieFile string      `yaml:"cookie_file"`
+	Login      LoginConfig `yaml:"login"`
+}
+
+// LoginConfig posts credentials to URL. For token profiles the token is read
+// from TokenPath and cached until ExpiresInPath (seconds) or TTLSeconds.
+type LoginConfig struct {
+	URL           string            `yaml:"url"`
+	Method        string            `yaml:"method"`
+	Body          string            `yaml:"body"`
+	Headers       map[string]string `yaml:"headers"`
+	TokenPath     string            `yaml:"token_path"`
+	ExpiresInPath string            `yaml:"expires_in_path"`
+	TTLSeconds    int               `yaml:"ttl_seconds"`
+}
+
+type SinkConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Stream  string `yaml:"stream"`
+	MaxLen  int64  `yaml:"max_len"`
+}
+
+type BreakerConfig struct {
+	FailureThreshold   int           `yaml:"failure_threshold"`
+	CooldownSeconds    int           `yaml:"cooldown_seconds"`
+	MaxCooldownSeconds int           `yaml:"max_cooldown_seconds"`
+	AlertAfterSeconds  int           `yaml:"alert_after_seconds"`
+	Alert              ChannelConfig `yaml:"alert"`
 }
 
 type LoggingConfig struct {
@@ -153,6 +413,41 @@ func (c Config) Validate() error {
 	if c.Network.Retry.MaxAttempts <= 0 {
 		return errors.New("network.retry.max_attempts must be > 0")
 	}
+	profiles := map[string]bool{}
+	for i, a := range c.AuthProfiles {
+		if strings.TrimSpace(a.Name) == "" {
+			return fmt.Errorf("auth_profiles[%d].name required", i)
+		}
+		if profiles[a.Name] {
+			return fmt.Errorf("auth_profiles[%d].name %q duplicated", i, a.Name)
+		}
+		profiles[a.Name] = true
+		switch strings.ToLower(a.Type) {
+		case "basic":
+			if a.Username == "" {
+				return fmt.Errorf("auth_profiles[%d].username required for basic", i)
+			}
+		case "bearer":
+			if a.Token == "" && a.TokenFile == "" {
+				return fmt.Errorf("auth_profiles[%d].token or token_file required for bearer", i)
+			}
+		case "cookie":
+			if a.Login.URL == "" && a.CookieFile == "" {
+				return fmt.Errorf("auth_profiles[%d].login.url or cookie_file required for cookie", i)
+			}
+		case "token":
+			if a.Login.URL == "" || a.Login.TokenPath == "" {
+				return fmt.Errorf("auth_profiles[%d].login.url and login.token_path required for token", i)
+			}
+		default:
+			return fmt.Errorf("auth_profiles[%d].type %q unsupported", i, a.Type)
+		}
+	}
+	for i, src := range c.Sources {
+		if src.Auth != "" && !profiles[src.Auth] {
+			return fmt.Errorf("sources[%d].auth references unknown profile %q", i, src.Auth)
+		}
+	}
 	for i, src := range c.Sources {
 		if strings.TrimSpace(src.Name) == "" {
 			return fmt.Errorf("sources[%d].name required", i)
@@ -160,16 +455,251 @@ func (c Config) Validate() error {
 		if strings.TrimSpace(src.Type) == "" {
 			return fmt.Errorf("sources[%d].type required", i)
 		}
-		if strings.TrimSpace(src.URL) == "" {
-			return fmt.Errorf("sources[%d].url required", i)
+		switch strings.ToLower(src.Type) {
+		case "ingest":
+			if src.Ingest.Token == "" && src.Ingest.Secret == "" {
+				return fmt.Errorf("sources[%d].ingest.token or ingest.secret required", i)
+			}
+		case "redis_stream":
+			if src.RedisStream.Stream == "" {
+				return fmt.Errorf("sources[%d].redis_stream.stream required", i)
+			}
+		default:
+			if strings.TrimSpace(src.URL) == "" {
+				return fmt.Errorf("sources[%d].url required", i)
+			}
+		}
+		if src.Charset != "" {
+			if enc, _ := charset.Lookup(src.Charset); enc == nil {
+				return fmt.Errorf("sources[%d].charset %q unsupported", i, src.Charset)
+			}
+		}
+		if strings.ToLower(src.Type) == "html" && strings.TrimSpace(src.Parser.HTML.Item) == "" {
+			return fmt.Errorf("sources[%d].parser.html.item required for html sources", i)
+		}
+		if tz := src.Parser.Time.Timezone; tz != "" {
+			if _, err := time.LoadLocation(tz); err != nil {
+				return fmt.Errorf("sources[%d].parser.time.timezone: %w", i, err)
+			}
+		}
+		for name, f := range src.Parser.Mapping.Fields {
+			if f.Regex != "" {
+				if _, err := regexp.Compile(f.Regex); err != nil {
+					return fmt.Errorf("sources[%d].parser.mapping.fields.%s.regex: %w", i, name, err)
+				}
+			}
+		}
+		switch pg := src.Parser.Pagination; strings.ToLower(pg.Mode) {
+		case "":
+		case "page", "offset":
+		case "cursor":
+			if pg.CursorPath == "" {
+				return fmt.Errorf("sources[%d].parser.pagination.cursor_path required for cursor mode", i)
+			}
+		default:
+			return fmt.Errorf("sources[%d].parser.pagination.mode %q unsupported", i, pg.Mode)
+		}
+		for phase, sec := range src.Polling.Intervals {
+			if !validPhase(phase) {
+				return fmt.Errorf("sources[%d].polling.intervals: unknown phase %q", i, phase)
+			}
+			if sec <= 0 {
+				return fmt.Errorf("sources[%d].polling.intervals.%s must be > 0", i, phase)
+			}
+		}
+		switch strings.ToUpper(src.Method) {
+		case "", "GET", "POST", "PUT":
+		default:
+			return fmt.Errorf("sources[%d].method %q unsupported", i, src.Method)
+		}
+		for j, sc := range src.Schedule {
+			if _, err := cron.Parse(sc.Cron); err != nil {
+				return fmt.Errorf("sources[%d].schedule[%d]: %w", i, j, err)
+			}
+		}
+		if src.Polling.MinSeconds < 0 || src.Polling.MaxSeconds < 0 {
+			return fmt.Errorf("sources[%d].polling min/max must be >= 0", i)
+		}
+		if src.Polling.MaxSeconds > 0 && src.Polling.MinSeconds > src.Polling.MaxSeconds {
+			return fmt.Errorf("sources[%d].polling.min_seconds > max_seconds", i)
+		}
+	}
+	for phase := range c.Scoring.MarketHours.Phases {
+		if !validPhase(phase) {
+			return fmt.Errorf("scoring.market_hours.phases: unknown phase %q", phase)
+		}
+	}
+	for i, t := range c.Topics {
+		if err := t.KeywordRules.validate(); err != nil {
+			return fmt.Errorf("topics[%d]: %w", i, err)
+		}
+	}
+	if err := c.Triggers.Strong.KeywordRules.validate(); err != nil {
+		return fmt.Errorf("triggers.strong: %w", err)
+	}
+	names := map[string]bool{}
+	for i, ch := range c.PushChannels() {
+		if strings.TrimSpace(ch.Name) == "" {
+			return fmt.Errorf("channels[%d].name required", i)
+		}
+		if names[ch.Name] {
+			return fmt.Errorf("channels[%d].name %q duplicated", i, ch.Name)
+		}
+		names[ch.Name] = true
+		switch strings.ToLower(ch.Type) {
+		case "dingtalk", "wecom", "feishu", "webhook":
+		default:
+			return fmt.Errorf("channels[%d].type %q unsupported", i, ch.Type)
+		}
+		if strings.TrimSpace(ch.Webhook) == "" {
+			return fmt.Errorf("channels[%d].webhook required", i)
+		}
+	}
+	if c.Digest.Enabled {
+		if c.Scoring.DigestThreshold <= 0 || c.Scoring.DigestThreshold >= c.Scoring.PushThreshold {
+			return errors.New("scoring.digest_threshold must be > 0 and < push_threshold when digest is enabled")
+		}
+		if len(c.Digest.Schedule) == 0 && c.Digest.IntervalMinutes <= 0 {
+			return errors.New("digest.schedule or digest.interval_minutes required")
+		}
+		for i, at := range c.Digest.Schedule {
+			if _, err := time.Parse("15:04", at); err != nil {
+				return fmt.Errorf("digest.schedule[%d] %q must be HH:MM", i, at)
+			}
+		}
+		for _, name := range c.Digest.Channels {
+			if !names[name] {
+				return fmt.Errorf("digest references unknown channel %q", name)
+			}
+		}
+	}
+	if len(c.Dedupe.KeyStrategy) > 0 && !hasExactKey(c.Dedupe.KeyStrategy) {
+		return errors.New("dedupe.key_strategy needs url, id, source_title or source_title_time besides simhash")
+	}
+	if d := c.Dedupe.SimHashDistance; d != nil && (*d < 0 || *d > 10) {
+		return errors.New("dedupe.simhash_distance must be between 0 and 10")
+	}
+	if c.Corroboration.Similarity < 0 || c.Corroboration.Similarity > 1 {
+		return errors.New("corroboration.similarity must be between 0 and 1")
+	}
+	if c.Breaker.Alert.Webhook != "" {
+		switch strings.ToLower(c.Breaker.Alert.Type) {
+		case "dingtalk", "wecom", "feishu", "webhook":
+		default:
+			return fmt.Errorf("breaker.alert.type %q unsupported", c.Breaker.Alert.Type)
+		}
+	}
+	reserveTTL := c.Dedupe.ReserveTTLSeconds
+	if reserveTTL <= 0 {
+		reserveTTL = 600
+	}
+	if reserveTTL <= c.Push.Queue.MaxAgeSeconds {
+		return errors.New("dedupe.reserve_ttl_seconds (default 600) must be > push.queue.max_age_seconds")
+	}
+	switch c.Push.Queue.OnExpire {
+	case "", "digest", "discard":
+	default:
+		return fmt.Errorf("push.queue.on_expire %q must be digest or discard", c.Push.Queue.OnExpire)
+	}
+	for i, r := range c.Routes {
+		if len(r.Channels) == 0 {
+			return fmt.Errorf("routes[%d].channels required", i)
+		}
+		for _, name := range r.Channels {
+			if !names[name] {
+				return fmt.Errorf("routes[%d] references unknown channel %q", i, name)
+			}
+		}
+		if r.MaxScore > 0 && r.MaxScore < r.MinScore {
+			return fmt.Errorf("routes[%d].max_score must be >= min_score", i)
 		}
 	}
 	return nil
 }
 
+func (r KeywordRules) validate() error {
+	for _, expr := range r.Regex {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("invalid regex %q: %w", expr, err)
+		}
+	}
+	return nil
+}
+
+// PushChannels returns the configured channels, falling back to the legacy
+// dingding section when no channels are declared.
+func (c Config) PushChannels() []ChannelConfig {
+	if len(c.Channels) > 0 {
+		out := make([]ChannelConfig, len(c.Channels))
+		for i, ch := range c.Channels {
+			if ch.Template == "" {
+				ch.Template = c.Push.Template.Markdown
+			}
+			if ch.PushThreshold <= 0 {
+				ch.PushThreshold = c.Scoring.PushThreshold
+			}
+			if ch.MaxPushPerMinute <= 0 {
+				ch.MaxPushPerMinute = c.Push.MaxPushPerMinute
+			}
+			out[i] = ch
+		}
+		return out
+	}
+	return []ChannelConfig{{
+		Name:      "dingding",
+		Type:      "dingtalk",
+		Webhook:   c.Dingding.Webhook,
+		Secret:    c.Dingding.Secret,
+		MsgType:   c.Dingding.MsgType,
+		Title:     c.Dingding.Title,
+		TimeoutMS: c.Dingding.TimeoutMS,
+		Template:  c.Push.Template.Markdown,
+
+		PushThreshold:    c.Scoring.PushThreshold,
+		MaxPushPerMinute: c.Push.MaxPushPerMinute,
+	}}
+}
+
 func (c *Config) ExpandEnv() {
 	c.Dingding.Webhook = os.ExpandEnv(c.Dingding.Webhook)
 	c.Dingding.Secret = os.ExpandEnv(c.Dingding.Secret)
+	for i := range c.Channels {
+		c.Channels[i].Webhook = os.ExpandEnv(c.Channels[i].Webhook)
+		c.Channels[i].Secret = os.ExpandEnv(c.Channels[i].Secret)
+		for k, v := range c.Channels[i].Headers {
+			c.Channels[i].Headers[k] = os.ExpandEnv(v)
+		}
+	}
+	for i := range c.Sources {
+		c.Sources[i].Ingest.Token = os.ExpandEnv(c.Sources[i].Ingest.Token)
+		c.Sources[i].Ingest.Secret = os.ExpandEnv(c.Sources[i].Ingest.Secret)
+	}
+	for i := range c.AuthProfiles {
+		c.AuthProfiles[i].Username = os.ExpandEnv(c.AuthProfiles[i].Username)
+		c.AuthProfiles[i].Password = os.ExpandEnv(c.AuthProfiles[i].Password)
+		c.AuthProfiles[i].Token = os.ExpandEnv(c.AuthProfiles[i].Token)
+	}
+	c.Breaker.Alert.Webhook = os.ExpandEnv(c.Breaker.Alert.Webhook)
+	c.Breaker.Alert.Secret = os.ExpandEnv(c.Breaker.Alert.Secret)
 	c.Redis.Addr = os.ExpandEnv(c.Redis.Addr)
 	c.Redis.Password = os.ExpandEnv(c.Redis.Password)
 }
+
+// hasExactKey reports whether strategy has a key other than simhash; the
+// digest and sink dedupe only by exact keys.
+func hasExactKey(strategy []string) bool {
+	for _, k := range strategy {
+		if k != "simhash" {
+			return true
+		}
+	}
+	return false
+}
+
+func validPhase(phase string) bool {
+	switch phase {
+	case "pre_open", "call_auction", "in_session", "lunch_break", "after_close", "non_trading_day":
+		return true
+	}
+	return false
+}
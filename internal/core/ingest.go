@@ -0,0 +1,149 @@
+package core
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"realtime-message/internal/config"
+	"realtime-message/internal/logging"
+	"realtime-message/internal/metrics"
+	"realtime-message/internal/model"
+	"realtime-message/internal/parser"
+)
+
+const maxIngestBody = 1 << 20
+
+// ingestHandler serves POST /ingest/{source} for sources of type "ingest"
+// and hands accepted messages to that source's worker. The worker set is
+// swapped on every config reload.
+type ingestHandler struct {
+	mu      sync.RWMutex
+	ctx     context.Context
+	workers map[string]*Worker
+	logger  *logging.Logger
+}
+
+func (h *ingestHandler) set(ctx context.Context, workers map[string]*Worker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ctx = ctx
+	h.workers = workers
+}
+
+func (h *ingestHandler) serve(ctx context.Context, addr string) {
+	if addr == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /ingest/{source}", h.handle)
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	go func() {
+		h.logger.Info("ingest listening", logging.Field{Key: "addr", Val: addr})
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			h.logger.Error("ingest server failed", logging.Field{Key: "err", Val: err})
+		}
+	}()
+}
+
+func (h *ingestHandler) handle(rw http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("source")
+	h.mu.RLock()
+	w, ctx := h.workers[name], h.ctx
+	h.mu.RUnlock()
+	if w == nil {
+		http.Error(rw, "unknown source", http.StatusNotFound)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxIngestBody+1))
+	if err != nil {
+		http.Error(rw, "read body", http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxIngestBody {
+		http.Error(rw, "body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if !authorized(w.source.Ingest, r, body) {
+		metrics.Inc(metrics.Name("ingest_rejected", name))
+		h.logger.Warn("ingest unauthorized", logging.Field{Key: "source", Val: name}, logging.Field{Key: "remote", Val: r.RemoteAddr})
+		http.Error(rw, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	msgs, err := parsePayload(w.source, body)
+	if err != nil {
+		h.logger.Warn("ingest parse failed", logging.Field{Key: "source", Val: name}, logging.Field{Key: "err", Val: err})
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	metrics.Add(metrics.Name("ingest_received", name), int64(len(msgs)))
+	h.logger.Info("ingested", logging.Field{Key: "source", Val: name}, logging.Field{Key: "count", Val: len(msgs)})
+	for _, m := range msgs {
+		w.handle(ctx, m)
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(rw).Encode(map[string]int{"accepted": len(msgs)})
+}
+
+// authorized checks the bearer token and/or the hex HMAC-SHA256 of the body
+// (optionally prefixed "sha256="), whichever the source configures.
+func authorized(cfg config.IngestConfig, r *http.Request, body []byte) bool {
+	if cfg.Token != "" {
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(cfg.Token)) != 1 {
+			return false
+		}
+	}
+	if cfg.Secret != "" {
+		header := cfg.SignatureHeader
+		if header == "" {
+			header = "X-Signature"
+		}
+		got, err := hex.DecodeString(strings.TrimPrefix(r.Header.Get(header), "sha256="))
+		if err != nil {
+			return false
+		}
+		mac := hmac.New(sha256.New, []byte(cfg.Secret))
+		mac.Write(body)
+		if !hmac.Equal(got, mac.Sum(nil)) {
+			return false
+		}
+	}
+	return cfg.Token != "" || cfg.Secret != ""
+}
+
+// parsePayload accepts a batch in any shape ParseJSON understands, or a
+// single message object.
+func parsePayload(src config.SourceConfig, body []byte) ([]model.Message, error) {
+	msgs, err := parser.ParseJSON(src.Name, body, src.Parser)
+	if err == nil && len(msgs) > 0 {
+		return msgs, nil
+	}
+	var obj map[string]any
+	if json.Unmarshal(body, &obj) != nil {
+		if err == nil {
+			err = errors.New("no messages in body")
+		}
+		return nil, err
+	}
+	single := src.Parser
+	single.Mapping.ListPath = ""
+	wrapped, _ := json.Marshal([]any{obj})
+	return parser.ParseJSON(src.Name, wrapped, single)
+}
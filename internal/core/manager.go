@@ -5,24 +5,38 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"realtime-message/internal/auth"
+	"realtime-message/internal/breaker"
+	"realtime-message/internal/calendar"
 	"realtime-message/internal/config"
+	"realtime-message/internal/corroborate"
 	"realtime-message/internal/dedupe"
+	"realtime-message/internal/digest"
 	"realtime-message/internal/logging"
+	"realtime-message/internal/metrics"
+	"realtime-message/internal/model"
 	"realtime-message/internal/push"
+	"realtime-message/internal/route"
 	"realtime-message/internal/scoring"
+	"realtime-message/internal/sink"
 )
 
 type Manager struct {
 	cfgPath string
 	logger  *logging.Logger
 	cancel  context.CancelFunc
+	router  *route.Router
+	ingest  *ingestHandler
+	// breakers outlive reloads so a failing source keeps its state
+	breakers map[string]*breaker.Breaker
 }
 
 func NewManager(cfgPath string, logger *logging.Logger) *Manager {
-	return &Manager{cfgPath: cfgPath, logger: logger}
+	return &Manager{cfgPath: cfgPath, logger: logger, ingest: &ingestHandler{logger: logger}, breakers: map[string]*breaker.Breaker{}}
 }
 
 func (m *Manager) Start(ctx context.Context) error {
@@ -33,14 +47,46 @@ func (m *Manager) Start(ctx context.Context) error {
 	if err := m.applyRuntime(cfg); err != nil {
 		return err
 	}
-	m.runWithConfig(ctx, cfg)
+	if err := m.runWithConfig(ctx, cfg); err != nil {
+		return err
+	}
+	metrics.Serve(ctx, cfg.Runtime.MetricsListen, m.logger)
+	m.ingest.serve(ctx, cfg.Runtime.IngestListen)
 	m.handleSignals(ctx)
 	m.handleReload(ctx, cfg.Runtime.ReloadIntervalSeconds)
 	<-ctx.Done()
 	return nil
 }
 
-func (m *Manager) runWithConfig(ctx context.Context, cfg config.Config) {
+func (m *Manager) runWithConfig(ctx context.Context, cfg config.Config) error {
+	channels := cfg.PushChannels()
+	pushers, err := push.NewAll(channels)
+	if err != nil {
+		return err
+	}
+	var alert push.Pusher
+	if cfg.Breaker.Alert.Webhook != "" {
+		if cfg.Breaker.Alert.Name == "" {
+			cfg.Breaker.Alert.Name = "ops"
+		}
+		if alert, err = push.New(cfg.Breaker.Alert); err != nil {
+			return err
+		}
+	}
+	cal, err := calendar.Load(cfg.Calendar)
+	if err != nil {
+		return err
+	}
+	scoreEngine, err := scoring.NewEngine(cfg.Topics, cfg.Triggers, cfg.Scoring, cal)
+	if err != nil {
+		return err
+	}
+	router := route.New(channels, cfg.Routes, cfg.Push.Queue, pushers, m.logger)
+	if m.router != nil {
+		m.router.Handover(router)
+		m.router.Stop()
+	}
+	m.router = router
 	if m.cancel != nil {
 		m.cancel()
 	}
@@ -48,32 +94,65 @@ func (m *Manager) runWithConfig(ctx context.Context, cfg config.Config) {
 	m.cancel = cancel
 
 	store := dedupe.New(cfg.Redis, cfg.Dedupe)
-	pusher := &push.DingTalk{
-		Webhook:  cfg.Dingding.Webhook,
-		Secret:   cfg.Dingding.Secret,
-		MsgType:  cfg.Dingding.MsgType,
-		Title:    cfg.Dingding.Title,
-		Timeout:  time.Duration(cfg.Dingding.TimeoutMS) * time.Millisecond,
-		Template: cfg.Push.Template.Markdown,
-	}
-	rate := push.NewRateLimiter(cfg.Push.MaxPushPerMinute)
-	scoreEngine := scoring.Engine{Topics: cfg.Topics, Triggers: cfg.Triggers, Scoring: cfg.Scoring}
+	var dg *digest.Digest
+	if cfg.Digest.Enabled {
+		dg = digest.New(cfg.Redis, cfg.Digest, cfg.Topics, pushers, router.Limiters(), m.logger)
+		go dg.Run(workerCtx)
+	}
+	var corr *corroborate.Tracker
+	if cfg.Corroboration.Enabled {
+		corr = corroborate.New(cfg.Redis, cfg.Corroboration)
+	}
+	var sk *sink.Sink
+	if cfg.Sink.Enabled {
+		sk = sink.New(cfg.Redis, cfg.Sink)
+	}
+	router.Run(workerCtx, func(ctx context.Context, msg model.ScoredMessage, reason string) {
+		if cfg.Push.Queue.OnExpire == "discard" || dg == nil {
+			return
+		}
+		collect(ctx, store, dg, m.logger, msg)
+	})
 
+	auth.SetProfiles(cfg.AuthProfiles)
 	scores := map[string]int{}
 	for _, src := range cfg.Sources {
 		scores[src.Name] = src.BaseScore
 	}
 	scoring.SetBaseScores(scores)
 
+	ingest := map[string]*Worker{}
+	breakers := map[string]*breaker.Breaker{}
 	for _, src := range cfg.Sources {
 		src := src
+		switch strings.ToLower(src.Type) {
+		case "ingest":
+			ingest[src.Name] = NewWorker(src, cfg.Network, scoreEngine, store, router, dg, corr, sk, nil, cal, m.logger)
+			continue
+		case "redis_stream":
+			go NewWorker(src, cfg.Network, scoreEngine, store, router, dg, corr, sk, nil, cal, m.logger).Consume(workerCtx, cfg.Redis)
+			continue
+		case "sse", "websocket":
+			go NewWorker(src, cfg.Network, scoreEngine, store, router, dg, corr, sk, nil, cal, m.logger).Stream(workerCtx)
+			continue
+		}
 		if src.PollIntervalSeconds <= 0 {
 			src.PollIntervalSeconds = cfg.Runtime.DefaultPollIntervalSeconds
 		}
-		worker := NewWorker(src, cfg.Network, scoreEngine, store, pusher, rate, m.logger)
+		brk, ok := m.breakers[src.Name]
+		if ok {
+			brk.Reconfigure(cfg.Breaker, alert)
+		} else {
+			brk = breaker.New(src.Name, cfg.Breaker, alert, m.logger)
+		}
+		breakers[src.Name] = brk
+		worker := NewWorker(src, cfg.Network, scoreEngine, store, router, dg, corr, sk, brk, cal, m.logger)
 		go worker.Run(workerCtx)
 	}
-	m.logger.Info("workers started", logging.Field{Key: "sources", Val: len(cfg.Sources)})
+	m.breakers = breakers
+	m.ingest.set(workerCtx, ingest)
+	m.logger.Info("workers started", logging.Field{Key: "sources", Val: len(cfg.Sources)}, logging.Field{Key: "channels", Val: len(pushers)})
+	return nil
 }
 
 func (m *Manager) handleSignals(ctx context.Context) {
@@ -120,7 +199,9 @@ func (m *Manager) reload(ctx context.Context, reason string) {
 		return
 	}
 	m.logger.Info("reloading", logging.Field{Key: "reason", Val: reason})
-	m.runWithConfig(ctx, cfg)
+	if err := m.runWithConfig(ctx, cfg); err != nil {
+		m.logger.Error("reload failed", logging.Field{Key: "err", Val: err})
+	}
 }
 
 func (m *Manager) applyRuntime(cfg config.Config) error {
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"realtime-message/internal/config"
+	"realtime-message/internal/dedupe"
+	"realtime-message/internal/model"
+	"realtime-message/internal/parser"
+)
+
+const defaultMaxPages = 5
+
+// FollowPages fetches the pages after the first while no item of the last
+// page is seen, up to max_pages in total and within the source's timeout
+// budget counted from start. It returns every item, the number of pages and
+// why it stopped; errors on later pages end the walk and keep what was read.
+func FollowPages(ctx context.Context, src config.SourceConfig, netcfg config.NetworkConfig, vars map[string]string, start time.Time, first []byte, msgs []model.Message, seen func(model.Message) bool) ([]model.Message, int, string) {
+	pg := src.Parser.Pagination
+	mode := strings.ToLower(pg.Mode)
+	if mode == "" {
+		return msgs, 1, ""
+	}
+	maxPages := pg.MaxPages
+	if maxPages <= 0 {
+		maxPages = defaultMaxPages
+	}
+	if vars == nil {
+		vars = TemplateVars(src, start, dedupe.Watermark{})
+	}
+	budget := time.Duration(clampTimeout(src.TimeoutMS, netcfg.DefaultTimeoutMS)) * time.Millisecond
+
+	all := msgs
+	keys := map[string]bool{}
+	for _, m := range msgs {
+		keys[pageKey(m)] = true
+	}
+	page, offset := pg.Start, pg.Start
+	if mode == "page" && page <= 0 {
+		page = 1
+	}
+	last, body := msgs, first
+	for pages := 1; ; pages++ {
+		if pages >= maxPages {
+			return all, pages, "max_pages"
+		}
+		if len(last) == 0 {
+			return all, pages, "empty"
+		}
+		for _, m := range last {
+			if seen(m) {
+				return all, pages, "seen"
+			}
+		}
+		if time.Since(start) >= budget {
+			return all, pages, "budget"
+		}
+		var value string
+		switch mode {
+		case "page":
+			page++
+			value = strconv.Itoa(page)
+		case "offset":
+			step := pg.Size
+			if step <= 0 {
+				step = len(last)
+			}
+			offset += step
+			value = strconv.Itoa(offset)
+		case "cursor":
+			value = parser.JSONValue(body, pg.CursorPath)
+			if value == "" {
+				return all, pages, "no_cursor"
+			}
+		}
+
+		next := src
+		pvars := make(map[string]string, len(vars)+1)
+		for k, v := range vars {
+			pvars[k] = v
+		}
+		pvars[mode] = value
+		if pg.Param != "" {
+			next.Query = make(map[string]string, len(src.Query)+1)
+			for k, v := range src.Query {
+				next.Query[k] = v
+			}
+			next.Query[pg.Param] = value
+		}
+		pctx, cancel := context.WithDeadline(ctx, start.Add(budget))
+		resp, err := Fetch(pctx, next, netcfg, nil, pvars)
+		cancel()
+		if err != nil {
+			return all, pages, "error: " + err.Error()
+		}
+		items, err := Parse(src, resp.Body)
+		if err != nil {
+			return all, pages, "error: " + err.Error()
+		}
+		last, body = last[:0:0], resp.Body
+		for _, m := range items {
+			if k := pageKey(m); !keys[k] {
+				keys[k] = true
+				last = append(last, m)
+			}
+		}
+		all = append(all, last...)
+	}
+}
+
+func pageKey(m model.Message) string {
+	return m.ID + "|" + m.URL + "|" + m.Title
+}
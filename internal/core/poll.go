@@ -0,0 +1,146 @@
+package core
+
+import (
+	"time"
+
+	"realtime-message/internal/calendar"
+	"realtime-message/internal/config"
+	"realtime-message/internal/cron"
+)
+
+const defaultMinPoll = 5 * time.Second
+
+// poller decides how long a worker waits between fetches. Sources with a
+// cron schedule fire at the scheduled times only. Otherwise the base interval
+// follows the market phase; in adaptive mode it is halved after a fetch with
+// new items and stretched by half after one without, within min/max.
+type poller struct {
+	schedules []scheduleEntry
+	base      time.Duration
+	intervals map[string]time.Duration
+	adaptive  bool
+	min       time.Duration
+	max       time.Duration
+	cal       *calendar.Calendar
+	factor    float64
+}
+
+type scheduleEntry struct {
+	cron        *cron.Schedule
+	tradingDays bool
+}
+
+func newPoller(src config.SourceConfig, cal *calendar.Calendar) *poller {
+	base := time.Duration(src.PollIntervalSeconds) * time.Second
+	if base <= 0 {
+		base = 60 * time.Second
+	}
+	p := &poller{
+		base:      base,
+		intervals: map[string]time.Duration{},
+		adaptive:  src.Polling.Adaptive,
+		min:       time.Duration(src.Polling.MinSeconds) * time.Second,
+		max:       time.Duration(src.Polling.MaxSeconds) * time.Second,
+		cal:       cal,
+		factor:    1,
+	}
+	for phase, sec := range src.Polling.Intervals {
+		p.intervals[phase] = time.Duration(sec) * time.Second
+	}
+	for _, sc := range src.Schedule {
+		// expressions were checked by config.Validate
+		if c, err := cron.Parse(sc.Cron); err == nil {
+			p.schedules = append(p.schedules, scheduleEntry{cron: c, tradingDays: sc.TradingDays})
+		}
+	}
+	return p
+}
+
+// wait returns how long to sleep after a fetch that began at start.
+// overran reports that the fetch took longer than the interval.
+func (p *poller) wait(start, now time.Time) (d time.Duration, overran bool) {
+	if len(p.schedules) > 0 {
+		next := p.nextScheduled(now)
+		if next.IsZero() {
+			return 24 * time.Hour, false
+		}
+		return next.Sub(now), false
+	}
+	interval := p.interval(now)
+	elapsed := now.Sub(start)
+	if elapsed >= interval {
+		return interval, true
+	}
+	return interval - elapsed, false
+}
+
+// nextScheduled returns the earliest upcoming time of any entry, skipping
+// non-trading days for entries restricted to them.
+func (p *poller) nextScheduled(now time.Time) time.Time {
+	var best time.Time
+	for _, e := range p.schedules {
+		t := e.cron.Next(now)
+		for i := 0; i < 400 && !t.IsZero() && e.tradingDays && p.cal != nil && !p.cal.IsTradingDay(t); i++ {
+			y, m, d := t.Date()
+			t = e.cron.Next(time.Date(y, m, d+1, 0, 0, 0, 0, time.Local).Add(-time.Second))
+		}
+		if t.IsZero() || (e.tradingDays && p.cal != nil && !p.cal.IsTradingDay(t)) {
+			continue
+		}
+		if best.IsZero() || t.Before(best) {
+			best = t
+		}
+	}
+	return best
+}
+
+func (p *poller) phaseBase(now time.Time) time.Duration {
+	if p.cal != nil && len(p.intervals) > 0 {
+		if d, ok := p.intervals[p.cal.Phase(now)]; ok {
+			return d
+		}
+	}
+	return p.base
+}
+
+// observe feeds the number of new items from the last fetch.
+func (p *poller) observe(now time.Time, fresh int) {
+	if !p.adaptive {
+		return
+	}
+	if fresh > 0 {
+		p.factor *= 0.5
+	} else {
+		p.factor *= 1.5
+	}
+	base := p.phaseBase(now)
+	p.factor = float64(p.clamp(base, time.Duration(float64(base)*p.factor))) / float64(base)
+}
+
+func (p *poller) interval(now time.Time) time.Duration {
+	base := p.phaseBase(now)
+	if !p.adaptive {
+		return base
+	}
+	return p.clamp(base, time.Duration(float64(base)*p.factor))
+}
+
+func (p *poller) clamp(base, d time.Duration) time.Duration {
+	lo, hi := p.min, p.max
+	if lo <= 0 {
+		lo = defaultMinPoll
+	}
+	if hi <= 0 {
+		hi = 10 * base
+	}
+	if lo > hi {
+		lo = hi
+	}
+	if d < lo {
+		return lo
+	}
+	if d > hi {
+		return hi
+	}
+	return d
+}
@@ -0,0 +1,73 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"realtime-message/internal/calendar"
+	"realtime-message/internal/config"
+)
+
+func TestPollerWait(t *testing.T) {
+	start := time.Date(2026, 10, 16, 10, 0, 0, 0, time.Local)
+	cases := []struct {
+		name    string
+		src     config.SourceConfig
+		fresh   []int
+		elapsed time.Duration
+		want    time.Duration
+		overran bool
+	}{
+		{"fixed", config.SourceConfig{PollIntervalSeconds: 60}, nil, 10 * time.Second, 50 * time.Second, false},
+		{"default interval", config.SourceConfig{}, nil, 0, 60 * time.Second, false},
+		{"overran", config.SourceConfig{PollIntervalSeconds: 60}, nil, 70 * time.Second, 60 * time.Second, true},
+		{"adaptive fresh", config.SourceConfig{PollIntervalSeconds: 60, Polling: config.PollingConfig{Adaptive: true}}, []int{3}, 0, 30 * time.Second, false},
+		{"adaptive idle", config.SourceConfig{PollIntervalSeconds: 60, Polling: config.PollingConfig{Adaptive: true}}, []int{0, 0}, 0, 135 * time.Second, false},
+		{"adaptive min", config.SourceConfig{PollIntervalSeconds: 60, Polling: config.PollingConfig{Adaptive: true, MinSeconds: 20}}, []int{1, 1, 1}, 0, 20 * time.Second, false},
+		{"adaptive max", config.SourceConfig{PollIntervalSeconds: 60, Polling: config.PollingConfig{Adaptive: true, MaxSeconds: 90}}, []int{0, 0, 0}, 0, 90 * time.Second, false},
+		{"adaptive recovers", config.SourceConfig{PollIntervalSeconds: 60, Polling: config.PollingConfig{Adaptive: true, MaxSeconds: 90}}, []int{0, 0, 0, 1}, 0, 45 * time.Second, false},
+		{"cron", config.SourceConfig{PollIntervalSeconds: 60, Schedule: []config.ScheduleConfig{{Cron: "0 11 * * *"}, {Cron: "30 10 * * *"}}}, nil, 5 * time.Second, 30*time.Minute - 5*time.Second, false},
+	}
+	for _, c := range cases {
+		p := newPoller(c.src, nil)
+		for _, n := range c.fresh {
+			p.observe(start, n)
+		}
+		d, overran := p.wait(start, start.Add(c.elapsed))
+		if d != c.want || overran != c.overran {
+			t.Errorf("%s: got %v %v, want %v %v", c.name, d, overran, c.want, c.overran)
+		}
+	}
+}
+
+func TestPollerWaitCalendar(t *testing.T) {
+	cal, err := calendar.Load(config.CalendarConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := config.SourceConfig{
+		PollIntervalSeconds: 60,
+		Polling:             config.PollingConfig{Intervals: map[string]int{calendar.InSession: 10, calendar.NonTradingDay: 600}},
+	}
+	cases := []struct {
+		now  time.Time
+		want time.Duration
+	}{
+		{time.Date(2026, 10, 16, 10, 0, 0, 0, time.Local), 10 * time.Second},
+		{time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local), 60 * time.Second},
+		{time.Date(2026, 10, 17, 10, 0, 0, 0, time.Local), 600 * time.Second},
+	}
+	p := newPoller(src, cal)
+	for _, c := range cases {
+		if d, _ := p.wait(c.now, c.now); d != c.want {
+			t.Errorf("%v: got %v, want %v", c.now, d, c.want)
+		}
+	}
+
+	// trading-day schedules skip the weekend
+	p = newPoller(config.SourceConfig{Schedule: []config.ScheduleConfig{{Cron: "0 9 * * *", TradingDays: true}}}, cal)
+	now := time.Date(2026, 10, 16, 15, 0, 0, 0, time.Local)
+	if d, _ := p.wait(now, now); d != time.Date(2026, 10, 19, 9, 0, 0, 0, time.Local).Sub(now) {
+		t.Errorf("trading days: got %v", d)
+	}
+}
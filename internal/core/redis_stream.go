@@ -0,0 +1,153 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"realtime-message/internal/config"
+	"realtime-message/internal/logging"
+	"realtime-message/internal/metrics"
+)
+
+// pendingRescan is how often entries that did not settle are read again.
+const pendingRescan = time.Minute
+
+// Consume reads a redis_stream source through its consumer group until ctx
+// ends. Entries left pending by an earlier run are processed first; each
+// entry is acknowledged once all of its messages settled.
+func (w *Worker) Consume(ctx context.Context, rcfg config.RedisConfig) {
+	rs := w.source.RedisStream
+	group := rs.Group
+	if group == "" {
+		group = "dingbot"
+	}
+	consumer := rs.Consumer
+	if consumer == "" {
+		consumer, _ = os.Hostname()
+	}
+	count := int64(rs.Count)
+	if count <= 0 {
+		count = 50
+	}
+	block := time.Duration(rs.BlockMS) * time.Millisecond
+	if block <= 0 {
+		block = 5 * time.Second
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:     rcfg.Addr,
+		Password: rcfg.Password,
+		DB:       rcfg.DB,
+	})
+	defer client.Close()
+
+	backoff := time.Second
+	ready, pending := false, true
+	// unsettled entries stay pending and are read again from the start of
+	// this consumer's pending list every pendingRescan
+	from, scanned := "0", time.Now()
+	for ctx.Err() == nil {
+		if !pending && time.Since(scanned) >= pendingRescan {
+			pending, from = true, "0"
+		}
+		var err error
+		if !ready {
+			err = client.XGroupCreateMkStream(ctx, rs.Stream, group, "0").Err()
+			if err == nil || strings.HasPrefix(err.Error(), "BUSYGROUP") {
+				ready, err = true, nil
+				w.logger.Info("stream group ready", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "stream", Val: rs.Stream}, logging.Field{Key: "group", Val: group})
+			}
+		}
+		if err == nil {
+			start := ">"
+			if pending {
+				start = from
+			}
+			var streams []redis.XStream
+			streams, err = client.XReadGroup(ctx, &redis.XReadGroupArgs{
+				Group:    group,
+				Consumer: consumer,
+				Streams:  []string{rs.Stream, start},
+				Count:    count,
+				Block:    block,
+			}).Result()
+			if errors.Is(err, redis.Nil) {
+				err = nil
+			}
+			if err == nil {
+				n := 0
+				for _, st := range streams {
+					for _, entry := range st.Messages {
+						settled := w.consumeEntry(ctx, entry)
+						if ctx.Err() != nil {
+							return
+						}
+						n++
+						from = entry.ID
+						if !settled {
+							continue
+						}
+						if err := client.XAck(ctx, rs.Stream, group, entry.ID).Err(); err != nil {
+							w.logger.Error("stream ack failed", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "id", Val: entry.ID}, logging.Field{Key: "err", Val: err})
+						}
+					}
+				}
+				if pending && n == 0 {
+					pending, scanned = false, time.Now()
+				}
+				backoff = time.Second
+				continue
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		w.logger.Error("stream read failed", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "err", Val: err}, logging.Field{Key: "retry_in_ms", Val: backoff.Milliseconds()})
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if backoff *= 2; backoff > 30*time.Second {
+			backoff = 30 * time.Second
+		}
+	}
+}
+
+// consumeEntry parses the payload field of entry, or the entry's fields as a
+// single message object when no payload field is configured or present. It
+// reports whether every message settled, i.e. whether entry may be acked;
+// payloads that cannot be parsed count as settled.
+func (w *Worker) consumeEntry(ctx context.Context, entry redis.XMessage) bool {
+	field := w.source.RedisStream.Field
+	if field == "" {
+		field = "data"
+	}
+	var payload []byte
+	if v, ok := entry.Values[field].(string); ok {
+		payload = []byte(v)
+	} else {
+		payload, _ = json.Marshal(entry.Values)
+	}
+	msgs, err := parsePayload(w.source, payload)
+	if err != nil {
+		metrics.Inc(metrics.Name("stream_unparsed", w.source.Name))
+		w.logger.Warn("stream entry unparsed", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "id", Val: entry.ID}, logging.Field{Key: "err", Val: err})
+		return true
+	}
+	metrics.Add(metrics.Name("stream_received", w.source.Name), int64(len(msgs)))
+	settled := true
+	for _, m := range msgs {
+		if !w.handle(ctx, m) {
+			settled = false
+		}
+	}
+	return settled
+}
@@ -0,0 +1,182 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"realtime-message/internal/auth"
+	"realtime-message/internal/config"
+	"realtime-message/internal/dedupe"
+	"realtime-message/internal/fetcher"
+	"realtime-message/internal/model"
+	"realtime-message/internal/parser"
+)
+
+// Validators are the cache validators of a source's last successful fetch,
+// replayed as If-None-Match / If-Modified-Since.
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+// Update keeps the validators of resp. Callers do so only once the body
+// parsed and all of its messages settled, so a bad or partly delivered
+// response is not hidden behind later 304s.
+func (v *Validators) Update(resp *fetcher.Response) {
+	v.ETag = resp.Header.Get("ETag")
+	v.LastModified = resp.Header.Get("Last-Modified")
+}
+
+// Fetch requests src once, with retries. vars fill ${...} templates in the
+// url, headers, query and body; nil means TemplateVars without a watermark.
+// v, if set, is sent as conditional headers.
+func Fetch(ctx context.Context, src config.SourceConfig, netcfg config.NetworkConfig, v *Validators, vars map[string]string) (*fetcher.Response, error) {
+	timeout := clampTimeout(src.TimeoutMS, netcfg.DefaultTimeoutMS)
+	retry := clampRetry(src.Retry, netcfg.Retry)
+
+	client := fetcher.New(time.Duration(timeout)*time.Millisecond, retry.RetryOnStatus, retry.MaxAttempts, retry.BackoffMS, retry.Multiplier, retry.JitterMS)
+
+	if vars == nil {
+		vars = TemplateVars(src, time.Now(), dedupe.Watermark{})
+	}
+	var prof *auth.Profile
+	if src.Auth != "" {
+		if prof = auth.Lookup(src.Auth); prof == nil {
+			return nil, fmt.Errorf("unknown auth profile %q", src.Auth)
+		}
+	}
+	do := func() (*fetcher.Response, error) {
+		req, err := NewRequest(src, vars)
+		if err != nil {
+			return nil, err
+		}
+		if prof != nil {
+			if err := prof.Apply(ctx, req); err != nil {
+				return nil, err
+			}
+		}
+		if v != nil && req.Method == http.MethodGet {
+			if v.ETag != "" {
+				req.Header.Set("If-None-Match", v.ETag)
+			}
+			if v.LastModified != "" {
+				req.Header.Set("If-Modified-Since", v.LastModified)
+			}
+		}
+		return client.Do(ctx, req)
+	}
+	resp, err := do()
+	var ferr *fetcher.Error
+	if prof != nil && errors.As(err, &ferr) && ferr.LastStatus == http.StatusUnauthorized {
+		// the session or token was rejected; log in again once
+		prof.Invalidate()
+		resp, err = do()
+	}
+	if prof != nil && resp != nil {
+		prof.Observe(resp.Header)
+	}
+	if err != nil || resp.NotModified() {
+		return resp, err
+	}
+	body, _, err := fetcher.Decode(resp.Body, resp.Header.Get("Content-Type"), src.Charset)
+	if err != nil {
+		return resp, fmt.Errorf("decode body: %w", err)
+	}
+	resp.Body = body
+	return resp, nil
+}
+
+// NewRequest builds the HTTP request for src with templates expanded.
+func NewRequest(src config.SourceConfig, vars map[string]string) (*http.Request, error) {
+	method := strings.ToUpper(src.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	u, err := url.Parse(expandTemplate(src.URL, vars))
+	if err != nil {
+		return nil, fmt.Errorf("source url: %w", err)
+	}
+	if len(src.Query) > 0 {
+		q := u.Query()
+		for k, val := range src.Query {
+			q.Set(k, expandTemplate(val, vars))
+		}
+		u.RawQuery = q.Encode()
+	}
+	var body io.Reader
+	if src.Body != "" {
+		body = strings.NewReader(expandTemplate(src.Body, vars))
+	}
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, val := range src.Headers {
+		req.Header.Set(k, expandTemplate(val, vars))
+	}
+	if src.Body != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+func Parse(src config.SourceConfig, body []byte) ([]model.Message, error) {
+	var msgs []model.Message
+	var err error
+	switch strings.ToLower(src.Type) {
+	case "rss":
+		msgs, err = parser.ParseRSS(src.Name, body, src.Parser)
+	case "html":
+		msgs, err = parser.ParseHTML(src.Name, src.URL, body, src.Parser)
+	default:
+		msgs, err = parser.ParseJSON(src.Name, body, src.Parser)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range msgs {
+		if msgs[i].Source == "" {
+			msgs[i].Source = src.Name
+		}
+	}
+	return msgs, nil
+}
+
+func clampTimeout(srcTimeout, defaultTimeout int) int {
+	if srcTimeout <= 0 {
+		srcTimeout = defaultTimeout
+	}
+	if srcTimeout > 10000 {
+		return 10000
+	}
+	return srcTimeout
+}
+
+func clampRetry(src, def config.RetryConfig) config.RetryConfig {
+	ret := src
+	if ret.MaxAttempts <= 0 {
+		ret.MaxAttempts = def.MaxAttempts
+	}
+	if ret.MaxAttempts > 3 {
+		ret.MaxAttempts = 3
+	}
+	if ret.BackoffMS <= 0 {
+		ret.BackoffMS = def.BackoffMS
+	}
+	if ret.Multiplier <= 0 {
+		ret.Multiplier = def.Multiplier
+	}
+	if ret.JitterMS <= 0 {
+		ret.JitterMS = def.JitterMS
+	}
+	if len(ret.RetryOnStatus) == 0 {
+		ret.RetryOnStatus = def.RetryOnStatus
+	}
+	return ret
+}
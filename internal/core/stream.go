@@ -0,0 +1,280 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"realtime-message/internal/auth"
+	"realtime-message/internal/dedupe"
+	"realtime-message/internal/logging"
+	"realtime-message/internal/metrics"
+)
+
+// Stream connection states reported as the stream_state.<source> gauge.
+const (
+	streamDisconnected = iota
+	streamConnecting
+	streamConnected
+)
+
+const (
+	defaultStreamIdle       = 120 * time.Second
+	defaultStreamMaxBackoff = 60 * time.Second
+)
+
+// lastEventIDs keeps each stream source's last event id across reloads, so
+// the new worker resumes where the old one stopped.
+var lastEventIDs sync.Map
+
+// Stream keeps an sse or websocket source connected until ctx ends,
+// reconnecting with exponential backoff from one second (or the SSE retry
+// field). Connections that stayed up for a while reset the backoff.
+func (w *Worker) Stream(ctx context.Context) {
+	maxBackoff := time.Duration(w.source.Stream.MaxBackoffSeconds) * time.Second
+	if maxBackoff <= 0 {
+		maxBackoff = defaultStreamMaxBackoff
+	}
+	if id, ok := lastEventIDs.Load(w.source.Name); ok {
+		w.lastEventID = id.(string)
+	}
+	backoff := time.Second
+	for ctx.Err() == nil {
+		w.setStreamState(streamConnecting)
+		start := time.Now()
+		var err error
+		if strings.ToLower(w.source.Type) == "sse" {
+			err = w.streamSSE(ctx)
+		} else {
+			err = w.streamWebSocket(ctx)
+		}
+		w.setStreamState(streamDisconnected)
+		if ctx.Err() != nil {
+			return
+		}
+		base := time.Second
+		if w.retry > 0 {
+			base = w.retry
+		}
+		if time.Since(start) > maxBackoff || backoff < base {
+			backoff = base
+		}
+		wait := backoff + time.Duration(rand.Int63n(int64(backoff)/2+1))
+		metrics.Inc(metrics.Name("stream_reconnects", w.source.Name))
+		w.logger.Warn("stream disconnected", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "err", Val: err}, logging.Field{Key: "retry_in_ms", Val: wait.Milliseconds()})
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func (w *Worker) setLastEventID(id string) {
+	w.lastEventID = id
+	lastEventIDs.Store(w.source.Name, id)
+}
+
+func (w *Worker) setStreamState(state int) {
+	metrics.Set(metrics.Name("stream_state", w.source.Name), int64(state))
+	if state == streamConnected {
+		w.logger.Info("stream connected", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "last_event_id", Val: w.lastEventID})
+	}
+}
+
+func (w *Worker) streamIdle() time.Duration {
+	if w.source.Stream.IdleTimeoutSeconds > 0 {
+		return time.Duration(w.source.Stream.IdleTimeoutSeconds) * time.Second
+	}
+	return defaultStreamIdle
+}
+
+func (w *Worker) streamVars() map[string]string {
+	vars := TemplateVars(w.source, time.Now(), dedupe.Watermark{})
+	vars["last_event_id"] = w.lastEventID
+	return vars
+}
+
+// streamSSE reads one SSE connection until it fails, goes idle or ctx ends.
+func (w *Worker) streamSSE(ctx context.Context) error {
+	cctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	idle := w.streamIdle()
+	watchdog := time.AfterFunc(idle, cancel)
+	defer watchdog.Stop()
+
+	req, err := NewRequest(w.source, w.streamVars())
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(cctx)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	if w.lastEventID != "" {
+		req.Header.Set("Last-Event-ID", w.lastEventID)
+	}
+	prof, err := w.applyAuth(cctx, req)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		if prof != nil && resp.StatusCode == http.StatusUnauthorized {
+			prof.Invalidate()
+		}
+		return errors.New(resp.Status)
+	}
+	w.setStreamState(streamConnected)
+
+	var event, id string
+	var data []string
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), maxIngestBody)
+	for scanner.Scan() {
+		watchdog.Reset(idle)
+		line := scanner.Text()
+		if line == "" {
+			if len(data) > 0 {
+				if id != "" {
+					w.setLastEventID(id)
+				}
+				if event == "" {
+					event = "message"
+				}
+				w.streamEvent(ctx, event, strings.Join(data, "\n"))
+			}
+			event, id, data = "", "", nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			id = value
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms > 0 {
+				w.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil && cctx.Err() == nil {
+		return err
+	}
+	if ctx.Err() == nil && cctx.Err() != nil {
+		return fmt.Errorf("idle for %s", idle)
+	}
+	return errors.New("stream closed")
+}
+
+// streamWebSocket reads one websocket connection; every text frame is a
+// payload. The id of the last handled message is kept as ${last_event_id}
+// for the url and subscribe templates.
+func (w *Worker) streamWebSocket(ctx context.Context) error {
+	vars := w.streamVars()
+	req, err := NewRequest(w.source, vars)
+	if err != nil {
+		return err
+	}
+	if _, err := w.applyAuth(ctx, req); err != nil {
+		return err
+	}
+	origin := *req.URL
+	origin.Path, origin.RawQuery = "/", ""
+	origin.Scheme = strings.Replace(strings.Replace(origin.Scheme, "wss", "https", 1), "ws", "http", 1)
+	cfg, err := websocket.NewConfig(req.URL.String(), origin.String())
+	if err != nil {
+		return err
+	}
+	cfg.Header = req.Header
+	cfg.Dialer = &net.Dialer{Timeout: 10 * time.Second}
+	ws, err := websocket.DialConfig(cfg)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+	stop := context.AfterFunc(ctx, func() { ws.Close() })
+	defer stop()
+	w.setStreamState(streamConnected)
+
+	if sub := w.source.Stream.Subscribe; sub != "" {
+		if err := websocket.Message.Send(ws, expandTemplate(sub, vars)); err != nil {
+			return fmt.Errorf("subscribe: %w", err)
+		}
+	}
+	idle := w.streamIdle()
+	for {
+		_ = ws.SetReadDeadline(time.Now().Add(idle))
+		var frame string
+		if err := websocket.Message.Receive(ws, &frame); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		w.streamEvent(ctx, "", frame)
+	}
+}
+
+func (w *Worker) streamEvent(ctx context.Context, event, data string) {
+	if event != "" && len(w.source.Stream.Events) > 0 && !contains(w.source.Stream.Events, event) {
+		return
+	}
+	msgs, err := parsePayload(w.source, []byte(data))
+	if err != nil {
+		metrics.Inc(metrics.Name("stream_unparsed", w.source.Name))
+		return
+	}
+	metrics.Add(metrics.Name("stream_received", w.source.Name), int64(len(msgs)))
+	for _, m := range msgs {
+		if strings.ToLower(w.source.Type) != "sse" && m.ID != "" {
+			w.setLastEventID(m.ID)
+		}
+		w.handle(ctx, m)
+	}
+}
+
+func (w *Worker) applyAuth(ctx context.Context, req *http.Request) (*auth.Profile, error) {
+	if w.source.Auth == "" {
+		return nil, nil
+	}
+	prof := auth.Lookup(w.source.Auth)
+	if prof == nil {
+		return nil, fmt.Errorf("unknown auth profile %q", w.source.Auth)
+	}
+	return prof, prof.Apply(ctx, req)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"realtime-message/internal/config"
+	"realtime-message/internal/dedupe"
+)
+
+// TemplateVars returns the ${...} values for a source's url, headers, query
+// and body. last_seen_* come from the watermark; without one, last_seen_time
+// falls back to now minus lookback_seconds.
+func TemplateVars(src config.SourceConfig, now time.Time, wm dedupe.Watermark) map[string]string {
+	seen := wm.Time
+	if seen.IsZero() {
+		seen = now.Add(-time.Duration(src.LookbackSeconds) * time.Second)
+	}
+	vars := map[string]string{
+		"now_unix":          strconv.FormatInt(now.Unix(), 10),
+		"now_unix_ms":       strconv.FormatInt(now.UnixMilli(), 10),
+		"date":              now.In(time.Local).Format("2006-01-02"),
+		"last_seen_time":    strconv.FormatInt(seen.Unix(), 10),
+		"last_seen_time_ms": strconv.FormatInt(seen.UnixMilli(), 10),
+		"last_seen_id":      wm.ID,
+	}
+	// the first page of a paginated source
+	switch pg := src.Parser.Pagination; strings.ToLower(pg.Mode) {
+	case "page":
+		vars["page"] = strconv.Itoa(max(pg.Start, 1))
+	case "offset":
+		vars["offset"] = strconv.Itoa(pg.Start)
+	case "cursor":
+		vars["cursor"] = ""
+	}
+	return vars
+}
+
+var placeholder = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandTemplate substitutes ${name} from vars, then environment variables.
+// Any other "$", and names set in neither, are left as written.
+func expandTemplate(s string, vars map[string]string) string {
+	if !strings.Contains(s, "${") {
+		return s
+	}
+	return placeholder.ReplaceAllStringFunc(s, func(m string) string {
+		k := m[2 : len(m)-1]
+		if v, ok := vars[k]; ok {
+			return v
+		}
+		if v, ok := os.LookupEnv(k); ok {
+			return v
+		}
+		return m
+	})
+}
+
+// usesLastSeen reports whether any template of src needs the watermark.
+func usesLastSeen(src config.SourceConfig) bool {
+	if strings.Contains(src.URL, "last_seen") || strings.Contains(src.Body, "last_seen") {
+		return true
+	}
+	for _, v := range src.Query {
+		if strings.Contains(v, "last_seen") {
+			return true
+		}
+	}
+	for _, v := range src.Headers {
+		if strings.Contains(v, "last_seen") {
+			return true
+		}
+	}
+	return false
+}
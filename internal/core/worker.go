@@ -3,210 +3,450 @@ package core
 import (
 	"context"
 	"errors"
-	"fmt"
-	"net/http"
-	"strings"
-	"sync/atomic"
+	"sort"
+	"sync"
 	"time"
 
+	"realtime-message/internal/breaker"
+	"realtime-message/internal/calendar"
 	"realtime-message/internal/config"
+	"realtime-message/internal/corroborate"
 	"realtime-message/internal/dedupe"
+	"realtime-message/internal/digest"
 	"realtime-message/internal/fetcher"
 	"realtime-message/internal/logging"
+	"realtime-message/internal/metrics"
 	"realtime-message/internal/model"
-	"realtime-message/internal/parser"
 	"realtime-message/internal/push"
+	"realtime-message/internal/route"
 	"realtime-message/internal/scoring"
+	"realtime-message/internal/sink"
 )
 
 type Worker struct {
-	source   config.SourceConfig
-	network  config.NetworkConfig
-	scoring  scoring.Engine
-	store    *dedupe.Store
-	pusher   *push.DingTalk
-	rate     *push.RateLimiter
-	logger   *logging.Logger
-	missed   atomic.Int64
+	source     config.SourceConfig
+	network    config.NetworkConfig
+	scoring    *scoring.Engine
+	store      *dedupe.Store
+	router     *route.Router
+	digest     *digest.Digest
+	corr       *corroborate.Tracker
+	sink       *sink.Sink
+	breaker    *breaker.Breaker
+	heldMu     sync.Mutex
+	held       map[string]time.Time
+	flightMu   sync.Mutex
+	flight     map[string]int
+	logger     *logging.Logger
+	poll       *poller
+	lastKeys   map[string]struct{}
+	validators Validators
+
+	// stream state for sse/websocket sources
+	lastEventID string
+	retry       time.Duration
 }
 
-func NewWorker(src config.SourceConfig, netcfg config.NetworkConfig, score scoring.Engine, store *dedupe.Store, pusher *push.DingTalk, rate *push.RateLimiter, logger *logging.Logger) *Worker {
+func NewWorker(src config.SourceConfig, netcfg config.NetworkConfig, score *scoring.Engine, store *dedupe.Store, router *route.Router, dg *digest.Digest, corr *corroborate.Tracker, sk *sink.Sink, brk *breaker.Breaker, cal *calendar.Calendar, logger *logging.Logger) *Worker {
 	return &Worker{
 		source:  src,
 		network: netcfg,
 		scoring: score,
 		store:   store,
-		pusher:  pusher,
-		rate:    rate,
+		router:  router,
+		digest:  dg,
+		corr:    corr,
+		sink:    sk,
+		breaker: brk,
+		poll:    newPoller(src, cal),
+		held:    map[string]time.Time{},
+		flight:  map[string]int{},
 		logger:  logger,
 	}
 }
 
 func (w *Worker) Run(ctx context.Context) {
-	interval := w.source.PollIntervalSeconds
-	if interval <= 0 {
-		interval = 60
-	}
-	ticker := time.NewTicker(time.Duration(interval) * time.Second)
-	defer ticker.Stop()
+	first, _ := w.poll.wait(time.Now(), time.Now())
+	timer := time.NewTimer(first)
+	defer timer.Stop()
 
-	var running atomic.Bool
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
-			if !running.CompareAndSwap(false, true) {
-				w.missed.Add(1)
-				w.logger.Warn("missed tick", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "missed", Val: w.missed.Load()})
-				continue
+		case <-timer.C:
+		}
+		start := time.Now()
+		if w.breaker == nil || w.breaker.Allow() {
+			fresh, err := w.fetchOnce(ctx)
+			if w.breaker != nil && ctx.Err() == nil {
+				w.breaker.Record(ctx, err)
 			}
-			start := time.Now()
-			w.fetchOnce(ctx)
-			w.logger.Info("fetch done", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "elapsed_ms", Val: time.Since(start).Milliseconds()})
-			running.Store(false)
+			w.poll.observe(time.Now(), fresh)
 		}
+		elapsed := time.Since(start)
+		wait, overran := w.poll.wait(start, time.Now())
+		if overran {
+			metrics.Inc(metrics.Name("poll_overrun", w.source.Name))
+			w.logger.Warn("fetch overran interval", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "elapsed_ms", Val: elapsed.Milliseconds()})
+		}
+		w.logger.Info("fetch done", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "elapsed_ms", Val: elapsed.Milliseconds()}, logging.Field{Key: "next_s", Val: wait.Seconds()})
+		timer.Reset(wait)
 	}
 }
 
-func (w *Worker) fetchOnce(ctx context.Context) {
-	timeout := clampTimeout(w.source.TimeoutMS, w.network.DefaultTimeoutMS)
-	retry := clampRetry(w.source.Retry, w.network.Retry)
-
-	client := fetcher.New(time.Duration(timeout)*time.Millisecond, retry.RetryOnStatus, retry.MaxAttempts, retry.BackoffMS, retry.Multiplier, retry.JitterMS)
-
-	req, _ := http.NewRequest("GET", w.source.URL, nil)
-	for k, v := range w.source.Headers {
-		req.Header.Set(k, v)
+// fetchOnce returns how many items were not in the previous fetch, for the
+// poller, and the fetch error, if any, for the breaker. Parse and downstream
+// errors are logged here and do not count.
+func (w *Worker) fetchOnce(ctx context.Context) (int, error) {
+	start := time.Now()
+	var wm dedupe.Watermark
+	if usesLastSeen(w.source) || (w.source.Incremental && w.source.Parser.Pagination.Mode != "") {
+		var err error
+		if wm, err = w.store.Watermark(ctx, w.source.Name); err != nil {
+			w.logger.Error("watermark load failed", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "err", Val: err})
+		}
 	}
-
-	status, body, err := client.Do(ctx, req)
+	vars := TemplateVars(w.source, start, wm)
+	resp, err := Fetch(ctx, w.source, w.network, &w.validators, vars)
 	if err != nil {
 		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 			w.logger.Info("fetch canceled", logging.Field{Key: "source", Val: w.source.Name})
-			return
+			return 0, nil
 		}
-		w.logger.Error("fetch failed", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "status", Val: status}, logging.Field{Key: "err", Val: err})
-		return
+		fields := []logging.Field{{Key: "source", Val: w.source.Name}, {Key: "err", Val: err}}
+		var ferr *fetcher.Error
+		if errors.As(err, &ferr) {
+			fields = append(fields, logging.Field{Key: "status", Val: ferr.LastStatus}, logging.Field{Key: "attempts", Val: ferr.Attempts}, logging.Field{Key: "elapsed_ms", Val: ferr.Elapsed.Milliseconds()})
+		}
+		w.logger.Error("fetch failed", fields...)
+		return 0, err
 	}
-
-	var msgs []model.Message
-	switch strings.ToLower(w.source.Type) {
-	case "rss":
-		msgs, err = parser.ParseRSS(w.source.Name, body)
-	default:
-		msgs, err = parser.ParseJSON(w.source.Name, body, w.source.Parser)
+	if resp.Attempts > 1 {
+		w.logger.Warn("fetch retried", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "attempts", Val: resp.Attempts})
+	}
+	if resp.NotModified() {
+		w.logger.Info("not modified", logging.Field{Key: "source", Val: w.source.Name})
+		return 0, nil
 	}
+
+	msgs, err := Parse(w.source, resp.Body)
 	if err != nil {
 		w.logger.Error("parse failed", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "err", Val: err})
+		return 0, nil
+	}
+	if w.source.Parser.Pagination.Mode != "" {
+		var pages int
+		var stop string
+		msgs, pages, stop = FollowPages(ctx, w.source, w.network, vars, start, resp.Body, msgs, func(m model.Message) bool {
+			return w.seenBefore(ctx, wm, m)
+		})
+		w.logger.Info("paged", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "pages", Val: pages}, logging.Field{Key: "stop", Val: stop})
+	}
+	fresh := w.countFresh(msgs)
+	w.logger.Info("parsed", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "count", Val: len(msgs)}, logging.Field{Key: "fresh", Val: fresh})
+
+	ok := true
+	if w.source.Incremental {
+		msgs, wm, ok = w.newerThanWatermark(ctx, msgs)
+	}
+	settled := make([]bool, len(msgs))
+	all := true
+	for i, m := range msgs {
+		settled[i] = w.handle(ctx, m)
+		all = all && settled[i]
+	}
+	if w.source.Incremental && ok {
+		w.advance(ctx, wm, msgs, settled)
+	}
+	w.keepValidators(resp, all)
+	return fresh, nil
+}
+
+// keepValidators saves the validators of resp only when every message in it
+// settled; otherwise they are cleared so the next poll gets the full body
+// again instead of a 304 hiding the messages still to retry.
+func (w *Worker) keepValidators(resp *fetcher.Response, settled bool) {
+	if settled {
+		w.validators.Update(resp)
 		return
 	}
-	w.logger.Info("parsed", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "count", Val: len(msgs)})
+	w.validators = Validators{}
+}
 
+// countFresh counts items missing from the previous fetch. The first fetch
+// after start only seeds the set.
+func (w *Worker) countFresh(msgs []model.Message) int {
+	keys := make(map[string]struct{}, len(msgs))
+	fresh := 0
 	for _, m := range msgs {
-		scored := w.scoring.Score(m)
-		if scored.Score < w.scoring.Scoring.PushThreshold {
-			continue
+		k := pageKey(m)
+		keys[k] = struct{}{}
+		if _, ok := w.lastKeys[k]; !ok && w.lastKeys != nil {
+			fresh++
 		}
-		seen, key, err := w.store.Seen(ctx, scored.Message)
-		if err != nil {
-			w.logger.Error("dedupe failed", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "err", Val: err})
-			continue
+	}
+	w.lastKeys = keys
+	return fresh
+}
+
+// seenBefore reports whether pagination has reached items this source
+// already delivered: at or below the high-water mark, in the previous fetch,
+// or already pushed to some channel.
+func (w *Worker) seenBefore(ctx context.Context, wm dedupe.Watermark, m model.Message) bool {
+	if w.source.Incremental && !wm.Time.IsZero() && !wm.After(m, 0) {
+		return true
+	}
+	if _, ok := w.lastKeys[pageKey(m)]; ok {
+		return true
+	}
+	for _, name := range w.router.Channels() {
+		if seen, _, err := w.store.Check(ctx, name, m); err == nil && seen {
+			return true
 		}
-		if seen {
-			w.logger.Info("dedupe hit", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "key", Val: key})
-			continue
+	}
+	return false
+}
+
+// newerThanWatermark drops items at or below the source's persisted
+// high-water mark. ok is false when the mark could not be loaded.
+func (w *Worker) newerThanWatermark(ctx context.Context, msgs []model.Message) ([]model.Message, dedupe.Watermark, bool) {
+	wm, err := w.store.Watermark(ctx, w.source.Name)
+	if err != nil {
+		w.logger.Error("watermark load failed", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "err", Val: err})
+		return msgs, wm, false
+	}
+	lookback := time.Duration(w.source.LookbackSeconds) * time.Second
+	fresh := msgs[:0]
+	for _, m := range msgs {
+		if wm.After(m, lookback) {
+			fresh = append(fresh, m)
 		}
-		if !w.rate.Allow() {
-			w.logger.Warn("rate limited", logging.Field{Key: "source", Val: w.source.Name})
-			continue
+	}
+	w.logger.Info("incremental", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "new", Val: len(fresh)}, logging.Field{Key: "skipped", Val: len(msgs) - len(fresh)})
+	return fresh, wm, true
+}
+
+// advance moves the high-water mark up through the oldest messages that are
+// settled, stopping at the first one still held, queued or failed so that it
+// is offered again on the next poll.
+func (w *Worker) advance(ctx context.Context, wm dedupe.Watermark, msgs []model.Message, settled []bool) {
+	order := make([]int, 0, len(msgs))
+	for i, m := range msgs {
+		if !m.TimeUnknown {
+			order = append(order, i)
 		}
-		content := w.render(scored)
-		w.logger.Info("push payload", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "len", Val: len(content)}, logging.Field{Key: "preview", Val: truncate(content, 200)})
-		if strings.ToLower(w.pusher.MsgType) == "text" {
-			err = w.pusher.SendText(content)
-		} else {
-			err = w.pusher.SendMarkdown(content)
+	}
+	sort.SliceStable(order, func(a, b int) bool { return msgs[order[a]].Time.Before(msgs[order[b]].Time) })
+	done := make([]model.Message, 0, len(order))
+	for _, i := range order {
+		if !settled[i] {
+			// items sharing its time would move the mark past it
+			for len(done) > 0 && done[len(done)-1].Time.Equal(msgs[i].Time) {
+				done = done[:len(done)-1]
+			}
+			break
 		}
-		if err != nil {
-			w.logger.Error("push failed", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "err", Val: err})
-		} else {
-			w.logger.Info("pushed", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "score", Val: scored.Score})
+		done = append(done, msgs[i])
+	}
+	if next := wm.Advance(done); next != wm {
+		if err := w.store.SetWatermark(ctx, w.source.Name, next); err != nil {
+			w.logger.Error("watermark save failed", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "err", Val: err})
 		}
 	}
 }
 
-func truncate(s string, max int) string {
-	if max <= 0 || len(s) <= max {
-		return s
+// handle scores and dispatches m. It reports whether m is settled: false
+// while it is held for corroboration or waiting in an outbox, or when it
+// could not be dispatched, so the caller can offer it again.
+func (w *Worker) handle(ctx context.Context, m model.Message) bool {
+	scored := w.scoring.Score(m)
+	if scoring.Blocked(scored) {
+		w.logger.Info("blacklisted", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "reason", Val: scored.Reasons[0]})
+		w.publish(ctx, scored, sink.Drop, nil)
+		return true
 	}
-	return s[:max] + "..."
+	if w.corr != nil {
+		n, err := w.corr.Observe(ctx, m)
+		if err != nil {
+			w.logger.Error("corroborate failed", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "err", Val: err})
+		} else {
+			scored = w.corr.Apply(scored, n)
+			if d, ok := w.corr.Hold(scored, w.scoring.Scoring.PushThreshold); ok && !w.corr.Corroborated(n) {
+				start, pending := w.markHeld(m, d)
+				if pending {
+					return false
+				}
+				if start {
+					w.logger.Info("held for corroboration", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "score", Val: scored.Score}, logging.Field{Key: "hold_s", Val: d.Seconds()})
+					w.publish(ctx, scored, sink.Held, nil)
+					time.AfterFunc(d, func() { w.release(ctx, m) })
+					return false
+				}
+			}
+		}
+	}
+	return w.dispatch(ctx, scored)
 }
 
-func (w *Worker) render(msg model.ScoredMessage) string {
-	if msg.Source == "" {
-		msg.Source = w.source.Name
-	}
-	if msg.Title == "" && msg.Content != "" {
-		msg.Title = msg.Content
+// markHeld starts a hold for msg unless one was started before. pending is
+// true while an earlier hold has not been released yet, so re-polls of the
+// same item neither hold it twice nor dispatch it early.
+func (w *Worker) markHeld(msg model.Message, d time.Duration) (start, pending bool) {
+	key := msg.Source + "|" + msg.ID + "|" + msg.URL + "|" + msg.Title
+	w.heldMu.Lock()
+	defer w.heldMu.Unlock()
+	now := time.Now()
+	for k, until := range w.held {
+		if now.Sub(until) > 4*d {
+			delete(w.held, k)
+		}
 	}
-	if msg.Content == "" && msg.Title != "" {
-		msg.Content = msg.Title
+	if until, ok := w.held[key]; ok {
+		return false, now.Before(until)
 	}
-	if msg.Time.IsZero() {
-		msg.Time = time.Now()
+	w.held[key] = now.Add(d)
+	return true, false
+}
+
+func (w *Worker) release(ctx context.Context, m model.Message) {
+	if ctx.Err() != nil {
+		return
 	}
-	if msg.Title == "" && msg.Content == "" {
-		msg.Title = "(no title)"
+	scored := w.scoring.Score(m)
+	n, err := w.corr.Count(ctx, m)
+	if err != nil {
+		w.logger.Error("corroborate failed", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "err", Val: err})
 	}
-	values := map[string]string{
-		"source":  msg.Source,
-		"title":   msg.Title,
-		"content": msg.Content,
-		"time":    msg.Time.Format("2006-01-02 15:04:05"),
-		"score":   fmt.Sprintf("%d", msg.Score),
-		"reasons": strings.Join(msg.Reasons, ","),
-		"link":    msg.URL,
+	scored = w.corr.Apply(scored, n)
+	w.logger.Info("hold released", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "score", Val: scored.Score}, logging.Field{Key: "sources", Val: n})
+	w.dispatch(ctx, scored)
+}
+
+// dispatch routes scored to its channels, or to the digest when none match.
+// It reports whether nothing is left in flight or failed for the message.
+func (w *Worker) dispatch(ctx context.Context, scored model.ScoredMessage) bool {
+	targets := w.router.Route(scored)
+	if len(targets) == 0 {
+		decision := sink.Drop
+		if scored.Score < w.scoring.Scoring.PushThreshold && scored.Score >= w.scoring.Scoring.DigestThreshold && w.collect(ctx, scored) {
+			decision = sink.Digest
+		}
+		w.publish(ctx, scored, decision, nil)
+		return true
 	}
-	if w.pusher.Template == "" {
-		return fmt.Sprintf("[%s] %s\n%s\n%s", msg.Source, msg.Title, msg.Content, msg.URL)
+	settled := true
+	var queued []string
+	for _, t := range targets {
+		res, ok, err := w.store.Reserve(ctx, t.Pusher.Name(), scored.Message)
+		if err != nil {
+			w.logger.Error("dedupe failed", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "channel", Val: t.Pusher.Name()}, logging.Field{Key: "err", Val: err})
+			settled = false
+			continue
+		}
+		if !ok {
+			w.logger.Info("dedupe hit", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "channel", Val: t.Pusher.Name()}, logging.Field{Key: "key", Val: res.Key})
+			if w.inFlight(scored.Message) {
+				settled = false
+			}
+			continue
+		}
+		t.Outbox.Enqueue(scored, w.track(scored.Message, res))
+		settled = false
+		queued = append(queued, t.Pusher.Name())
+		w.logger.Info("queued", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "channel", Val: t.Pusher.Name()}, logging.Field{Key: "score", Val: scored.Score})
 	}
-	rendered := push.RenderTemplate(w.pusher.Template, values)
-	if strings.TrimSpace(rendered) == "" {
-		return fmt.Sprintf("[%s] %s\n%s\n%s", msg.Source, msg.Title, msg.Content, msg.URL)
+	if len(queued) > 0 {
+		w.publish(ctx, scored, sink.Queued, queued)
+	} else if settled {
+		w.publish(ctx, scored, sink.Duplicate, nil)
 	}
-	return rendered
+	return settled
 }
 
-func clampTimeout(srcTimeout, defaultTimeout int) int {
-	if srcTimeout <= 0 {
-		srcTimeout = defaultTimeout
-	}
-	if srcTimeout > 10000 {
-		return 10000
+// trackedAck counts a queued message as in flight until its outbox reports
+// back, so the high-water mark does not pass it before it is delivered.
+type trackedAck struct {
+	push.Ack
+	w   *Worker
+	key string
+}
+
+func (w *Worker) track(msg model.Message, ack push.Ack) push.Ack {
+	key := pageKey(msg)
+	w.flightMu.Lock()
+	w.flight[key]++
+	w.flightMu.Unlock()
+	return &trackedAck{Ack: ack, w: w, key: key}
+}
+
+func (a *trackedAck) Commit(ctx context.Context) error {
+	defer a.done()
+	return a.Ack.Commit(ctx)
+}
+
+func (a *trackedAck) Release(ctx context.Context) error {
+	defer a.done()
+	return a.Ack.Release(ctx)
+}
+
+func (a *trackedAck) done() {
+	a.w.flightMu.Lock()
+	defer a.w.flightMu.Unlock()
+	if a.w.flight[a.key]--; a.w.flight[a.key] <= 0 {
+		delete(a.w.flight, a.key)
 	}
-	return srcTimeout
 }
 
-func clampRetry(src, def config.RetryConfig) config.RetryConfig {
-	ret := src
-	if ret.MaxAttempts <= 0 {
-		ret.MaxAttempts = def.MaxAttempts
+func (w *Worker) inFlight(msg model.Message) bool {
+	w.flightMu.Lock()
+	defer w.flightMu.Unlock()
+	return w.flight[pageKey(msg)] > 0
+}
+
+// publish records the decision for scored on the output stream, if any.
+// Each message is recorded once, plus once more when it was held first, so
+// re-polls do not repeat it.
+func (w *Worker) publish(ctx context.Context, scored model.ScoredMessage, decision string, channels []string) {
+	if w.sink == nil {
+		return
 	}
-	if ret.MaxAttempts > 3 {
-		ret.MaxAttempts = 3
+	scope := "sink"
+	if decision == sink.Held {
+		scope = "sink:held"
 	}
-	if ret.BackoffMS <= 0 {
-		ret.BackoffMS = def.BackoffMS
+	if seen, _, err := w.store.SeenIn(ctx, scope, scored.Message); err != nil {
+		w.logger.Error("dedupe failed", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "err", Val: err})
+	} else if seen {
+		return
+	}
+	if err := w.sink.Publish(ctx, scored, decision, channels); err != nil {
+		w.logger.Error("sink publish failed", logging.Field{Key: "source", Val: w.source.Name}, logging.Field{Key: "err", Val: err})
 	}
-	if ret.Multiplier <= 0 {
-		ret.Multiplier = def.Multiplier
+}
+
+func (w *Worker) collect(ctx context.Context, scored model.ScoredMessage) bool {
+	return collect(ctx, w.store, w.digest, w.logger, scored)
+}
+
+// collect adds scored to the digest unless it is already there, reporting
+// whether it did.
+func collect(ctx context.Context, store *dedupe.Store, dg *digest.Digest, logger *logging.Logger, scored model.ScoredMessage) bool {
+	if dg == nil {
+		return false
+	}
+	seen, _, err := store.SeenIn(ctx, "digest", scored.Message)
+	if err != nil {
+		logger.Error("dedupe failed", logging.Field{Key: "source", Val: scored.Source}, logging.Field{Key: "err", Val: err})
+		return false
 	}
-	if ret.JitterMS <= 0 {
-		ret.JitterMS = def.JitterMS
+	if seen {
+		return false
 	}
-	if len(ret.RetryOnStatus) == 0 {
-		ret.RetryOnStatus = def.RetryOnStatus
+	if err := dg.Add(ctx, scored); err != nil {
+		logger.Error("digest add failed", logging.Field{Key: "source", Val: scored.Source}, logging.Field{Key: "err", Val: err})
+		return false
 	}
-	return ret
+	logger.Info("digest queued", logging.Field{Key: "source", Val: scored.Source}, logging.Field{Key: "score", Val: scored.Score})
+	return true
 }
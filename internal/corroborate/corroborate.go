@@ -0,0 +1,177 @@
+package corroborate
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"realtime-message/internal/config"
+	"realtime-message/internal/dedupe"
+	"realtime-message/internal/model"
+	"realtime-message/internal/rdb"
+)
+
+const maxTitleRunes = 64
+
+type Tracker struct {
+	client     *redis.Client
+	key        string
+	window     time.Duration
+	similarity float64
+	minSources int
+	bonus      int
+	hold       time.Duration
+	holdMargin int
+}
+
+func New(rcfg config.RedisConfig, cfg config.CorroborationConfig) *Tracker {
+	client := rdb.Client(rcfg)
+	window := time.Duration(cfg.WindowMinutes) * time.Minute
+	if window <= 0 {
+		window = 10 * time.Minute
+	}
+	similarity := cfg.Similarity
+	if similarity <= 0 {
+		similarity = 0.6
+	}
+	minSources := cfg.MinSources
+	if minSources < 2 {
+		minSources = 2
+	}
+	return &Tracker{
+		client:     client,
+		key:        rcfg.KeyPrefix + "corroborate:recent",
+		window:     window,
+		similarity: similarity,
+		minSources: minSources,
+		bonus:      cfg.Bonus,
+		hold:       time.Duration(cfg.HoldSeconds) * time.Second,
+		holdMargin: cfg.HoldMargin,
+	}
+}
+
+// Observe records msg in the sliding window and returns how many distinct
+// sources (including msg's own) reported a similar title around its time.
+func (t *Tracker) Observe(ctx context.Context, msg model.Message) (int, error) {
+	title := normalizedTitle(msg)
+	if title == "" {
+		return 1, nil
+	}
+	at := msg.Time
+	if at.IsZero() {
+		at = time.Now()
+	}
+	member := msg.Source + "\x00" + title
+	_, err := t.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, t.key, redis.Z{Score: float64(at.UnixMilli()), Member: member})
+		pipe.ZRemRangeByScore(ctx, t.key, "-inf", strconv.FormatInt(time.Now().Add(-2*t.window).UnixMilli(), 10))
+		pipe.Expire(ctx, t.key, 2*t.window)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return t.count(ctx, msg.Source, title, at)
+}
+
+// Count is Observe without recording msg.
+func (t *Tracker) Count(ctx context.Context, msg model.Message) (int, error) {
+	title := normalizedTitle(msg)
+	if title == "" {
+		return 1, nil
+	}
+	at := msg.Time
+	if at.IsZero() {
+		at = time.Now()
+	}
+	return t.count(ctx, msg.Source, title, at)
+}
+
+func (t *Tracker) count(ctx context.Context, source, title string, at time.Time) (int, error) {
+	members, err := t.client.ZRangeByScore(ctx, t.key, &redis.ZRangeBy{
+		Min: strconv.FormatInt(at.Add(-t.window).UnixMilli(), 10),
+		Max: strconv.FormatInt(at.Add(t.window).UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return 0, err
+	}
+	sources := map[string]bool{source: true}
+	for _, m := range members {
+		src, other, ok := strings.Cut(m, "\x00")
+		if !ok || sources[src] {
+			continue
+		}
+		if Similarity(title, other) >= t.similarity {
+			sources[src] = true
+		}
+	}
+	return len(sources), nil
+}
+
+// Apply adds the corroboration bonus and reason when n sources agree.
+func (t *Tracker) Apply(scored model.ScoredMessage, n int) model.ScoredMessage {
+	if n < t.minSources {
+		return scored
+	}
+	scored.Score += t.bonus
+	scored.Reasons = append(scored.Reasons, "corroborated:"+strconv.Itoa(n))
+	return scored
+}
+
+func (t *Tracker) Corroborated(n int) bool {
+	return n >= t.minSources
+}
+
+// Hold reports how long an uncorroborated message should wait for a second
+// source: only messages within holdMargin below the push threshold are held.
+func (t *Tracker) Hold(scored model.ScoredMessage, threshold int) (time.Duration, bool) {
+	if t.hold <= 0 || scored.Score >= threshold || scored.Score < threshold-t.holdMargin {
+		return 0, false
+	}
+	return t.hold, true
+}
+
+// Similarity is the Jaccard index of the character bigrams of a and b.
+func Similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	sa, sb := bigrams(a), bigrams(b)
+	if len(sa) == 0 || len(sb) == 0 {
+		return 0
+	}
+	inter := 0
+	for k := range sa {
+		if sb[k] {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(sa)+len(sb)-inter)
+}
+
+func bigrams(s string) map[string]bool {
+	runes := []rune(s)
+	out := map[string]bool{}
+	if len(runes) == 1 {
+		out[s] = true
+	}
+	for i := 0; i+1 < len(runes); i++ {
+		out[string(runes[i:i+2])] = true
+	}
+	return out
+}
+
+func normalizedTitle(msg model.Message) string {
+	title := msg.Title
+	if title == "" {
+		title = msg.Content
+	}
+	runes := []rune(dedupe.Normalize(title))
+	if len(runes) > maxTitleRunes {
+		runes = runes[:maxTitleRunes]
+	}
+	return string(runes)
+}
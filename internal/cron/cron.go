@@ -0,0 +1,135 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression. Five fields (minute hour dom month
+// dow) or six with a leading seconds field are accepted, evaluated in
+// time.Local.
+type Schedule struct {
+	sec, min, hour, dom, month, dow uint64
+	domAny, dowAny                  bool
+}
+
+type bounds struct {
+	name     string
+	min, max int
+}
+
+var (
+	secBounds   = bounds{"second", 0, 59}
+	minBounds   = bounds{"minute", 0, 59}
+	hourBounds  = bounds{"hour", 0, 23}
+	domBounds   = bounds{"day of month", 1, 31}
+	monthBounds = bounds{"month", 1, 12}
+	dowBounds   = bounds{"day of week", 0, 7}
+)
+
+func Parse(expr string) (*Schedule, error) {
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("cron %q: want 5 or 6 fields, got %d", expr, len(fields))
+	}
+	s := &Schedule{domAny: fields[3] == "*" || fields[3] == "?", dowAny: fields[5] == "*" || fields[5] == "?"}
+	var err error
+	for i, f := range []struct {
+		dst *uint64
+		b   bounds
+	}{{&s.sec, secBounds}, {&s.min, minBounds}, {&s.hour, hourBounds}, {&s.dom, domBounds}, {&s.month, monthBounds}, {&s.dow, dowBounds}} {
+		if *f.dst, err = parseField(fields[i], f.b); err != nil {
+			return nil, fmt.Errorf("cron %q: %w", expr, err)
+		}
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	return s, nil
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%s: invalid step %q", b.name, part)
+			}
+			step = n
+			part = part[:i]
+		}
+		lo, hi := b.min, b.max
+		switch {
+		case part == "*" || part == "?":
+		case strings.Contains(part, "-"):
+			ends := strings.SplitN(part, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(ends[0])
+			hi, err2 = strconv.Atoi(ends[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("%s: invalid range %q", b.name, part)
+			}
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, fmt.Errorf("%s: invalid value %q", b.name, part)
+			}
+			lo = n
+			if step == 1 {
+				hi = n
+			}
+		}
+		if lo < b.min || hi > b.max || lo > hi {
+			return 0, fmt.Errorf("%s: %q out of range %d-%d", b.name, part, b.min, b.max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Next returns the first matching time strictly after t, or the zero time if
+// nothing matches within five years.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.In(time.Local).Truncate(time.Second).Add(time.Second)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		y, mo, d := t.Date()
+		h, mi, _ := t.Clock()
+		switch {
+		case s.month&(1<<uint(mo)) == 0:
+			t = time.Date(y, mo+1, 1, 0, 0, 0, 0, time.Local)
+		case !s.dayMatches(t):
+			t = time.Date(y, mo, d+1, 0, 0, 0, 0, time.Local)
+		case s.hour&(1<<uint(h)) == 0:
+			t = time.Date(y, mo, d, h+1, 0, 0, 0, time.Local)
+		case s.min&(1<<uint(mi)) == 0:
+			t = time.Date(y, mo, d, h, mi+1, 0, 0, time.Local)
+		case s.sec&(1<<uint(t.Second())) == 0:
+			t = t.Add(time.Second)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches follows the usual cron rule: when both day fields are
+// restricted, either one matching is enough.
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
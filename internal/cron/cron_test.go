@@ -0,0 +1,44 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func at(y int, mo time.Month, d, h, mi, s int) time.Time {
+	return time.Date(y, mo, d, h, mi, s, 0, time.Local)
+}
+
+func TestNext(t *testing.T) {
+	cases := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"*/15 * * * *", at(2026, 10, 16, 10, 7, 30), at(2026, 10, 16, 10, 15, 0)},
+		{"*/15 * * * *", at(2026, 10, 16, 10, 15, 0), at(2026, 10, 16, 10, 30, 0)},
+		{"0 9 * * 1-5", at(2026, 10, 16, 9, 0, 0), at(2026, 10, 19, 9, 0, 0)},
+		{"30 0 9 * * *", at(2026, 10, 16, 9, 0, 0), at(2026, 10, 16, 9, 0, 30)},
+		{"0 12 * * 7", at(2026, 10, 16, 0, 0, 0), at(2026, 10, 18, 12, 0, 0)},
+		{"0 0 1,15 * 0", at(2026, 10, 16, 0, 0, 0), at(2026, 10, 18, 0, 0, 0)},
+		{"0 0 1 * ?", at(2026, 12, 5, 0, 0, 0), at(2027, 1, 1, 0, 0, 0)},
+		{"0 0 30 2 *", at(2026, 10, 16, 0, 0, 0), time.Time{}},
+	}
+	for _, c := range cases {
+		s, err := Parse(c.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", c.expr, err)
+		}
+		if got := s.Next(c.from); !got.Equal(c.want) {
+			t.Errorf("%s from %v: got %v, want %v", c.expr, c.from, got, c.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, expr := range []string{"* * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "* * * 13 *", "a * * * *"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("%s: want error", expr)
+		}
+	}
+}
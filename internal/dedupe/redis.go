@@ -9,32 +9,48 @@ import (
 
 	"realtime-message/internal/config"
 	"realtime-message/internal/model"
+	"realtime-message/internal/rdb"
 )
 
 type Store struct {
-	client    *redis.Client
-	prefix    string
-	keyStrategy []string
-	ttl        time.Duration
+	client          *redis.Client
+	prefix          string
+	keyStrategy     []string
+	ttl             time.Duration
+	reserveTTL      time.Duration
+	simhashDistance int
+	legacyScope     string
 }
 
 func New(cfg config.RedisConfig, dcfg config.DedupeConfig) *Store {
-	client := redis.NewClient(&redis.Options{
-		Addr:     cfg.Addr,
-		Password: cfg.Password,
-		DB:       cfg.DB,
-	})
+	client := rdb.Client(cfg)
 	ttl := time.Duration(dcfg.TTLHours) * time.Hour
 	if ttl <= 0 {
 		ttl = 72 * time.Hour
 	}
-	return &Store{client: client, prefix: cfg.KeyPrefix, keyStrategy: dcfg.KeyStrategy, ttl: ttl}
+	reserveTTL := time.Duration(dcfg.ReserveTTLSeconds) * time.Second
+	if reserveTTL <= 0 {
+		reserveTTL = 10 * time.Minute
+	}
+	// unset means 3; 0 matches identical fingerprints only
+	distance := 3
+	if dcfg.SimHashDistance != nil {
+		distance = *dcfg.SimHashDistance
+	}
+	legacy := dcfg.LegacyChannel
+	if legacy == "" {
+		legacy = "dingding"
+	}
+	return &Store{client: client, prefix: cfg.KeyPrefix, keyStrategy: dcfg.KeyStrategy, ttl: ttl, reserveTTL: reserveTTL, simhashDistance: distance, legacyScope: legacy}
 }
 
-func (s *Store) Seen(ctx context.Context, msg model.Message) (bool, string, error) {
+// SeenIn reports whether msg was already recorded in scope and records it
+// otherwise, so the same message can be tracked independently per consumer
+// (e.g. the digest pool).
+func (s *Store) SeenIn(ctx context.Context, scope string, msg model.Message) (bool, string, error) {
 	keys := buildKeys(s.keyStrategy, msg)
 	for _, k := range keys {
-		full := s.prefix + k
+		full := s.scopedKey(scope, k)
 		ok, err := s.client.SetNX(ctx, full, 1, s.ttl).Result()
 		if err != nil {
 			return false, k, err
@@ -67,7 +83,7 @@ func buildKeys(strategy []string, msg model.Message) []string {
 				keys = append(keys, fmt.Sprintf("st:%s:%s", msg.Source, msg.Title))
 			}
 		case "source_title_time":
-			if msg.Title != "" {
+			if msg.Title != "" && !msg.TimeUnknown {
 				keys = append(keys, fmt.Sprintf("stt:%s:%s:%s", msg.Source, msg.Title, msg.Time.Format(time.RFC3339)))
 			}
 		}
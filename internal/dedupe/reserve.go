@@ -0,0 +1,167 @@
+package dedupe
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"realtime-message/internal/model"
+)
+
+var commitScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("SET", KEYS[1], "1", "PX", ARGV[2])
+end
+return false
+`)
+
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Reservation is a short-lived claim on a dedupe key. It becomes a regular
+// dedupe entry on Commit and is dropped on Release so the message can be
+// retried on a later poll.
+type Reservation struct {
+	store  *Store
+	Key    string
+	full   string
+	token  string
+	scope  string
+	fp     uint64
+	member string
+}
+
+// Reserve claims the message for scope (usually a channel name). ok is false
+// when the message was already delivered or is in flight elsewhere.
+func (s *Store) Reserve(ctx context.Context, scope string, msg model.Message) (*Reservation, bool, error) {
+	res := &Reservation{store: s, scope: scope}
+	keys := buildKeys(s.keyStrategy, msg)
+	if len(keys) > 0 {
+		seen, err := s.legacySeen(ctx, scope, keys[0])
+		if err != nil {
+			return nil, false, err
+		}
+		if seen {
+			return &Reservation{store: s, Key: keys[0]}, false, nil
+		}
+		full := s.scopedKey(scope, keys[0])
+		token := newToken()
+		ok, err := s.client.SetNX(ctx, full, token, s.reserveTTL).Result()
+		if err != nil {
+			return nil, false, err
+		}
+		if !ok {
+			return &Reservation{store: s, Key: keys[0]}, false, nil
+		}
+		res.Key, res.full, res.token = keys[0], full, token
+	}
+	if s.useSimHash() {
+		if fp := messageFingerprint(msg); fp != 0 {
+			tag := res.token
+			if tag == "" {
+				tag = newToken()
+			}
+			member := fingerprintMember(fp, tag)
+			other, dup, err := s.claimFingerprint(ctx, scope, fp, member)
+			if err != nil || dup {
+				_ = res.Release(ctx)
+				if err != nil {
+					return nil, false, err
+				}
+				return &Reservation{store: s, Key: fmt.Sprintf("simhash:%x~%x", fp, other)}, false, nil
+			}
+			res.fp, res.member = fp, member
+		}
+	}
+	return res, true, nil
+}
+
+func (r *Reservation) Commit(ctx context.Context) error {
+	if r.fp != 0 {
+		if err := r.store.storeFingerprint(ctx, r.scope, r.fp, r.member, r.store.ttl); err != nil {
+			return err
+		}
+	}
+	if r.token == "" {
+		return nil
+	}
+	return ignoreNil(commitScript.Run(ctx, r.store.client, []string{r.full}, r.token, r.store.ttl.Milliseconds()).Err())
+}
+
+func (r *Reservation) Release(ctx context.Context) error {
+	if r.fp != 0 {
+		if err := r.store.removeFingerprint(ctx, r.scope, r.fp, r.member); err != nil {
+			return err
+		}
+	}
+	if r.token == "" {
+		return nil
+	}
+	return ignoreNil(releaseScript.Run(ctx, r.store.client, []string{r.full}, r.token).Err())
+}
+
+func (s *Store) scopedKey(scope, k string) string {
+	if scope == "" {
+		return s.prefix + k
+	}
+	return s.prefix + scope + ":" + k
+}
+
+func newToken() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func ignoreNil(err error) error {
+	if err == redis.Nil {
+		return nil
+	}
+	return err
+}
+
+// Check reports whether Reserve would reject msg for scope, without writing.
+func (s *Store) Check(ctx context.Context, scope string, msg model.Message) (bool, string, error) {
+	if s.useSimHash() {
+		if fp := messageFingerprint(msg); fp != 0 {
+			other, dup, err := s.nearDuplicate(ctx, scope, fp)
+			if err != nil {
+				return false, "", err
+			}
+			if dup {
+				return true, fmt.Sprintf("simhash:%x~%x", fp, other), nil
+			}
+		}
+	}
+	keys := buildKeys(s.keyStrategy, msg)
+	if len(keys) == 0 {
+		return false, "", nil
+	}
+	n, err := s.client.Exists(ctx, s.scopedKey(scope, keys[0])).Result()
+	if err != nil {
+		return false, keys[0], err
+	}
+	if n == 0 {
+		seen, err := s.legacySeen(ctx, scope, keys[0])
+		return seen, keys[0], err
+	}
+	return true, keys[0], nil
+}
+
+// legacySeen reports whether k was recorded before dedupe keys were kept per
+// channel. Those unscoped keys still count for the legacy channel until they
+// expire.
+func (s *Store) legacySeen(ctx context.Context, scope, k string) (bool, error) {
+	if scope != s.legacyScope {
+		return false, nil
+	}
+	n, err := s.client.Exists(ctx, s.prefix+k).Result()
+	return n > 0, err
+}
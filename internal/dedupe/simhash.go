@@ -0,0 +1,218 @@
+package dedupe
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/redis/go-redis/v9"
+
+	"realtime-message/internal/model"
+)
+
+// Normalize folds full-width characters to half-width, lowercases and drops
+// punctuation, symbols and whitespace so that trivially different renderings
+// of the same headline compare equal.
+func Normalize(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '　':
+			continue
+		case r >= '！' && r <= '～':
+			r -= 0xfee0
+		}
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// SimHash returns the 64-bit SimHash of text over character bigrams.
+func SimHash(text string) uint64 {
+	runes := []rune(Normalize(text))
+	if len(runes) == 0 {
+		return 0
+	}
+	var weights [64]int
+	add := func(feature string) {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(feature))
+		v := h.Sum64()
+		for i := 0; i < 64; i++ {
+			if v&(1<<uint(i)) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+	if len(runes) == 1 {
+		add(string(runes))
+	}
+	for i := 0; i+1 < len(runes); i++ {
+		add(string(runes[i : i+2]))
+	}
+	var fp uint64
+	for i, w := range weights {
+		if w > 0 {
+			fp |= 1 << uint(i)
+		}
+	}
+	return fp
+}
+
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+func messageFingerprint(msg model.Message) uint64 {
+	return SimHash(msg.Title + " " + msg.Content)
+}
+
+func (s *Store) useSimHash() bool {
+	for _, k := range s.keyStrategy {
+		if k == "simhash" {
+			return true
+		}
+	}
+	return false
+}
+
+// Fingerprints are bucketed by distance+1 bands; by pigeonhole any two
+// fingerprints within the distance share at least one band exactly.
+func (s *Store) bandKeys(scope string, fp uint64) []string {
+	n := s.simhashDistance + 1
+	width := 64 / n
+	keys := make([]string, n)
+	for i := 0; i < n; i++ {
+		shift := uint(i * width)
+		size := width
+		if i == n-1 {
+			size = 64 - i*width
+		}
+		band := fp >> shift
+		if size < 64 {
+			band &= 1<<uint(size) - 1
+		}
+		keys[i] = s.scopedKey(scope, fmt.Sprintf("simhash:%d:%d:%x", n, i, band))
+	}
+	return keys
+}
+
+// claimScript checks every band of a fingerprint for a live member within
+// the distance and, when there is none, adds the new member to all bands, in
+// one step so that two sources reporting the same story at once cannot both
+// pass. Members are "<16 hex digits>:<tag>"; the 64-bit fingerprints are
+// compared as two 32-bit halves.
+var claimScript = redis.NewScript(`
+local function pop(x)
+	local c = 0
+	while x ~= 0 do
+		x = bit.band(x, x - 1)
+		c = c + 1
+	end
+	return c
+end
+local hi, lo = tonumber(ARGV[4]), tonumber(ARGV[5])
+local dist = tonumber(ARGV[6])
+for _, key in ipairs(KEYS) do
+	redis.call("ZREMRANGEBYSCORE", key, "-inf", ARGV[1])
+	for _, m in ipairs(redis.call("ZRANGEBYSCORE", key, "(" .. ARGV[1], "+inf")) do
+		local h = string.match(m, "^%x+")
+		if h then
+			h = string.rep("0", 16 - #h) .. h
+			local mhi, mlo = tonumber(string.sub(h, 1, 8), 16), tonumber(string.sub(h, 9, 16), 16)
+			if pop(bit.bxor(mhi, hi)) + pop(bit.bxor(mlo, lo)) <= dist then
+				return m
+			end
+		end
+	end
+end
+for _, key in ipairs(KEYS) do
+	redis.call("ZADD", key, ARGV[2], ARGV[3])
+	redis.call("PEXPIRE", key, ARGV[7])
+end
+return false
+`)
+
+// fingerprintMember makes the sorted-set member of one reservation, so that
+// releasing it never removes another claim on the same fingerprint.
+func fingerprintMember(fp uint64, tag string) string {
+	return fmt.Sprintf("%016x:%s", fp, tag)
+}
+
+func memberFingerprint(m string) (uint64, bool) {
+	h, _, _ := strings.Cut(m, ":")
+	fp, err := strconv.ParseUint(h, 16, 64)
+	return fp, err == nil
+}
+
+// claimFingerprint atomically reports a near-duplicate of fp or reserves
+// member in every band for reserve_ttl.
+func (s *Store) claimFingerprint(ctx context.Context, scope string, fp uint64, member string) (uint64, bool, error) {
+	now := time.Now()
+	res, err := claimScript.Run(ctx, s.client, s.bandKeys(scope, fp),
+		now.UnixMilli(), now.Add(s.reserveTTL).UnixMilli(), member,
+		fp>>32, fp&0xffffffff, s.simhashDistance, s.ttl.Milliseconds()).Result()
+	if err == redis.Nil {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	m, _ := res.(string)
+	other, _ := memberFingerprint(m)
+	return other, true, nil
+}
+
+func (s *Store) nearDuplicate(ctx context.Context, scope string, fp uint64) (uint64, bool, error) {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	for _, key := range s.bandKeys(scope, fp) {
+		members, err := s.client.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: "(" + now, Max: "+inf"}).Result()
+		if err != nil {
+			return 0, false, err
+		}
+		for _, m := range members {
+			other, ok := memberFingerprint(m)
+			if !ok {
+				continue
+			}
+			if Distance(fp, other) <= s.simhashDistance {
+				return other, true, nil
+			}
+		}
+	}
+	return 0, false, nil
+}
+
+func (s *Store) storeFingerprint(ctx context.Context, scope string, fp uint64, member string, ttl time.Duration) error {
+	now := time.Now()
+	expireAt := float64(now.Add(ttl).UnixMilli())
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range s.bandKeys(scope, fp) {
+			pipe.ZAdd(ctx, key, redis.Z{Score: expireAt, Member: member})
+			pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.UnixMilli(), 10))
+			pipe.Expire(ctx, key, s.ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *Store) removeFingerprint(ctx context.Context, scope string, fp uint64, member string) error {
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range s.bandKeys(scope, fp) {
+			pipe.ZRem(ctx, key, member)
+		}
+		return nil
+	})
+	return err
+}
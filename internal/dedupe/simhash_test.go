@@ -0,0 +1,86 @@
+package dedupe
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"Ｈｅｌｌｏ，　World!", "helloworld"},
+		{"【快讯】A股 大涨 3%", "快讯a股大涨3"},
+		{"  ", ""},
+	}
+	for _, c := range cases {
+		if got := Normalize(c.in); got != c.want {
+			t.Errorf("%q: got %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestSimHash(t *testing.T) {
+	cases := []struct {
+		a, b string
+		max  int
+	}{
+		{"A股三大指数集体收涨", "Ａ股 三大指数集体收涨！", 0},
+		{"央行宣布下调存款准备金率0.5个百分点", "央行宣布下调存款准备金率0.5个百分点，释放长期资金", 16},
+	}
+	for _, c := range cases {
+		if d := Distance(SimHash(c.a), SimHash(c.b)); d > c.max {
+			t.Errorf("%q vs %q: distance %d > %d", c.a, c.b, d, c.max)
+		}
+	}
+	if SimHash("！？ ") != 0 {
+		t.Error("empty text should hash to 0")
+	}
+}
+
+func TestBandKeys(t *testing.T) {
+	s := &Store{prefix: "p:", simhashDistance: 0}
+	if got := s.bandKeys("ch", 0x0123456789abcdef); len(got) != 1 || got[0] != "p:ch:simhash:1:0:123456789abcdef" {
+		t.Fatalf("got %v", got)
+	}
+	s.simhashDistance = 3
+	fp := uint64(0x0123456789abcdef)
+	cases := []struct {
+		other uint64
+		share bool
+	}{
+		{fp ^ 1 ^ 1<<20 ^ 1<<40, true},
+		{fp ^ 1<<63, true},
+		{fp ^ 1 ^ 1<<16 ^ 1<<32 ^ 1<<48, false},
+	}
+	keys := s.bandKeys("ch", fp)
+	if len(keys) != 4 {
+		t.Fatalf("got %d bands", len(keys))
+	}
+	for _, c := range cases {
+		shared := false
+		for i, k := range s.bandKeys("ch", c.other) {
+			shared = shared || k == keys[i]
+		}
+		if shared != c.share {
+			t.Errorf("%x: shared=%v, want %v", c.other, shared, c.share)
+		}
+	}
+}
+
+func TestFingerprintMember(t *testing.T) {
+	cases := []struct {
+		member string
+		fp     uint64
+		ok     bool
+	}{
+		{fingerprintMember(0xabc, "t1"), 0xabc, true},
+		{fingerprintMember(0xffffffffffffffff, "t2"), 0xffffffffffffffff, true},
+		{"abc", 0xabc, true},
+		{"zz:t", 0, false},
+	}
+	for _, c := range cases {
+		fp, ok := memberFingerprint(c.member)
+		if fp != c.fp || ok != c.ok {
+			t.Errorf("%q: got %x %v", c.member, fp, ok)
+		}
+	}
+	if fingerprintMember(1, "a") == fingerprintMember(1, "b") {
+		t.Error("members of different claims must differ")
+	}
+}
@@ -0,0 +1,63 @@
+package dedupe
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"realtime-message/internal/model"
+)
+
+// Watermark is the newest message a source has already handed to scoring.
+type Watermark struct {
+	Time time.Time
+	ID   string
+}
+
+func (s *Store) Watermark(ctx context.Context, source string) (Watermark, error) {
+	vals, err := s.client.HGetAll(ctx, s.prefix+"hwm:"+source).Result()
+	if err != nil {
+		return Watermark{}, err
+	}
+	var wm Watermark
+	if ms, err := strconv.ParseInt(vals["time"], 10, 64); err == nil {
+		wm.Time = time.UnixMilli(ms)
+	}
+	wm.ID = vals["id"]
+	return wm, nil
+}
+
+func (s *Store) SetWatermark(ctx context.Context, source string, wm Watermark) error {
+	key := s.prefix + "hwm:" + source
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, "time", wm.Time.UnixMilli(), "id", wm.ID)
+		pipe.Expire(ctx, key, s.ttl)
+		return nil
+	})
+	return err
+}
+
+// After reports whether msg is newer than the watermark, allowing lookback
+// so that recent items are still re-offered (e.g. to retry failed pushes).
+func (wm Watermark) After(msg model.Message, lookback time.Duration) bool {
+	if wm.Time.IsZero() || msg.TimeUnknown {
+		return true
+	}
+	cutoff := wm.Time.Add(-lookback)
+	if msg.Time.After(cutoff) {
+		return true
+	}
+	return msg.Time.Equal(cutoff) && lookback == 0 && msg.ID != "" && msg.ID != wm.ID
+}
+
+// Advance returns the watermark moved up to the newest of msgs.
+func (wm Watermark) Advance(msgs []model.Message) Watermark {
+	for _, m := range msgs {
+		if !m.TimeUnknown && m.Time.After(wm.Time) {
+			wm = Watermark{Time: m.Time, ID: m.ID}
+		}
+	}
+	return wm
+}
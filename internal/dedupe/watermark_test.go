@@ -0,0 +1,55 @@
+package dedupe
+
+import (
+	"testing"
+	"time"
+
+	"realtime-message/internal/model"
+)
+
+func TestWatermarkAfter(t *testing.T) {
+	base := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	wm := Watermark{Time: base, ID: "a"}
+	cases := []struct {
+		name     string
+		wm       Watermark
+		msg      model.Message
+		lookback time.Duration
+		want     bool
+	}{
+		{"no watermark", Watermark{}, model.Message{Time: base.Add(-time.Hour)}, 0, true},
+		{"newer", wm, model.Message{Time: base.Add(time.Second)}, 0, true},
+		{"older", wm, model.Message{Time: base.Add(-time.Second)}, 0, false},
+		{"same item", wm, model.Message{Time: base, ID: "a"}, 0, false},
+		{"same time, other id", wm, model.Message{Time: base, ID: "b"}, 0, true},
+		{"same time, no id", wm, model.Message{Time: base}, 0, false},
+		{"within lookback", wm, model.Message{Time: base.Add(-time.Minute)}, 2 * time.Minute, true},
+		{"before lookback", wm, model.Message{Time: base.Add(-3 * time.Minute)}, 2 * time.Minute, false},
+		{"unknown time", wm, model.Message{Time: base.Add(-time.Hour), TimeUnknown: true}, 0, true},
+	}
+	for _, c := range cases {
+		if got := c.wm.After(c.msg, c.lookback); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestWatermarkAdvance(t *testing.T) {
+	base := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name string
+		msgs []model.Message
+		want Watermark
+	}{
+		{"empty", nil, Watermark{Time: base, ID: "a"}},
+		{"newest wins", []model.Message{{Time: base.Add(time.Minute), ID: "b"}, {Time: base.Add(2 * time.Minute), ID: "c"}, {Time: base.Add(time.Second), ID: "d"}}, Watermark{Time: base.Add(2 * time.Minute), ID: "c"}},
+		{"older ignored", []model.Message{{Time: base.Add(-time.Minute), ID: "b"}}, Watermark{Time: base, ID: "a"}},
+		{"unknown time ignored", []model.Message{{Time: base.Add(time.Hour), ID: "b", TimeUnknown: true}}, Watermark{Time: base, ID: "a"}},
+	}
+	for _, c := range cases {
+		got := Watermark{Time: base, ID: "a"}.Advance(c.msgs)
+		if !got.Time.Equal(c.want.Time) || got.ID != c.want.ID {
+			t.Errorf("%s: got %+v, want %+v", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package digest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"realtime-message/internal/config"
+	"realtime-message/internal/logging"
+	"realtime-message/internal/model"
+	"realtime-message/internal/push"
+	"realtime-message/internal/rdb"
+)
+
+const otherTopic = "其他"
+
+type Digest struct {
+	client  *redis.Client
+	key     string
+	lastKey string
+	cfg     config.DigestConfig
+	topics  []string
+	pushers []push.Pusher
+	limits  map[string]*push.RateLimiter
+	logger  *logging.Logger
+}
+
+// New builds the digest. limits are the channels' push rate limiters, which
+// digest sends share with regular pushes.
+func New(rcfg config.RedisConfig, cfg config.DigestConfig, topics []config.TopicConfig, pushers []push.Pusher, limits map[string]*push.RateLimiter, logger *logging.Logger) *Digest {
+	client := rdb.Client(rcfg)
+	names := make([]string, 0, len(topics))
+	for _, t := range topics {
+		names = append(names, t.Name)
+	}
+	if len(cfg.Channels) > 0 {
+		wanted := map[string]bool{}
+		for _, name := range cfg.Channels {
+			wanted[name] = true
+		}
+		selected := []push.Pusher{}
+		for _, p := range pushers {
+			if wanted[p.Name()] {
+				selected = append(selected, p)
+			}
+		}
+		pushers = selected
+	}
+	if cfg.MaxItems <= 0 {
+		cfg.MaxItems = 20
+	}
+	if cfg.Title == "" {
+		cfg.Title = "消息汇总"
+	}
+	return &Digest{
+		client:  client,
+		key:     rcfg.KeyPrefix + "digest:pending",
+		lastKey: rcfg.KeyPrefix + "digest:last",
+		cfg:     cfg,
+		topics:  names,
+		pushers: pushers,
+		limits:  limits,
+		logger:  logger,
+	}
+}
+
+func (d *Digest) Add(ctx context.Context, msg model.ScoredMessage) error {
+	buf, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return d.client.RPush(ctx, d.key, buf).Err()
+}
+
+// Run flushes on schedule. The last flush time is kept in Redis so reloads
+// and restarts continue the schedule instead of starting it over.
+func (d *Digest) Run(ctx context.Context) {
+	last := d.lastFlush(ctx)
+	for {
+		next := d.next(last)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		last = next
+		if now := time.Now(); now.Sub(next) > time.Minute {
+			// missed while stopped; flush once and continue from now
+			last = now
+		}
+		if err := d.client.Set(ctx, d.lastKey, last.UnixMilli(), 0).Err(); err != nil {
+			d.logger.Error("digest save failed", logging.Field{Key: "err", Val: err})
+		}
+		if err := d.Flush(ctx); err != nil {
+			d.logger.Error("digest flush failed", logging.Field{Key: "err", Val: err})
+		}
+	}
+}
+
+func (d *Digest) lastFlush(ctx context.Context) time.Time {
+	ms, err := d.client.Get(ctx, d.lastKey).Int64()
+	if err == nil {
+		return time.UnixMilli(ms)
+	}
+	now := time.Now()
+	if err != redis.Nil {
+		d.logger.Error("digest load failed", logging.Field{Key: "err", Val: err})
+		return now
+	}
+	if err := d.client.SetNX(ctx, d.lastKey, now.UnixMilli(), 0).Err(); err != nil {
+		d.logger.Error("digest save failed", logging.Field{Key: "err", Val: err})
+	}
+	return now
+}
+
+func (d *Digest) next(last time.Time) time.Time {
+	var next time.Time
+	if d.cfg.IntervalMinutes > 0 {
+		next = last.Add(time.Duration(d.cfg.IntervalMinutes) * time.Minute)
+	}
+	for _, at := range d.cfg.Schedule {
+		hm, err := time.Parse("15:04", at)
+		if err != nil {
+			continue
+		}
+		t := time.Date(last.Year(), last.Month(), last.Day(), hm.Hour(), hm.Minute(), 0, 0, time.Local)
+		if !t.After(last) {
+			t = t.AddDate(0, 0, 1)
+		}
+		if next.IsZero() || t.Before(next) {
+			next = t
+		}
+	}
+	return next
+}
+
+// Flush sends the pooled items to every channel. A channel that fails keeps
+// its items in its own retry list, ahead of the next flush's items, so the
+// channels that succeeded do not get them twice.
+func (d *Digest) Flush(ctx context.Context) error {
+	var shared *redis.StringSliceCmd
+	retries := make([]*redis.StringSliceCmd, len(d.pushers))
+	_, err := d.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		shared = pipe.LRange(ctx, d.key, 0, -1)
+		pipe.Del(ctx, d.key)
+		for i, p := range d.pushers {
+			retries[i] = pipe.LRange(ctx, d.retryKey(p.Name()), 0, -1)
+			pipe.Del(ctx, d.retryKey(p.Name()))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	var lastErr error
+	for i, p := range d.pushers {
+		raws := append(append([]string{}, retries[i].Val()...), shared.Val()...)
+		if err := d.send(ctx, p, raws); err != nil {
+			d.logger.Error("digest push failed", logging.Field{Key: "channel", Val: p.Name()}, logging.Field{Key: "count", Val: len(raws)}, logging.Field{Key: "err", Val: err})
+			lastErr = err
+			d.restore(ctx, p.Name(), raws)
+		}
+	}
+	return lastErr
+}
+
+func (d *Digest) retryKey(channel string) string {
+	return d.key + ":" + channel
+}
+
+func (d *Digest) send(ctx context.Context, p push.Pusher, raws []string) error {
+	msgs := make([]model.ScoredMessage, 0, len(raws))
+	for _, raw := range raws {
+		var m model.ScoredMessage
+		if err := json.Unmarshal([]byte(raw), &m); err != nil {
+			continue
+		}
+		msgs = append(msgs, m)
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	if rl := d.limits[p.Name()]; rl != nil {
+		if err := rl.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	body := d.render(msgs)
+	summary := model.ScoredMessage{Message: model.Message{Title: d.cfg.Title, Content: body, Source: "digest", Time: time.Now()}}
+	if err := p.Send(ctx, summary, body); err != nil {
+		return err
+	}
+	d.logger.Info("digest pushed", logging.Field{Key: "channel", Val: p.Name()}, logging.Field{Key: "count", Val: len(msgs)})
+	return nil
+}
+
+// restore puts raws back at the head of the channel's retry list, in order.
+func (d *Digest) restore(ctx context.Context, channel string, raws []string) {
+	if len(raws) == 0 {
+		return
+	}
+	vals := make([]any, 0, len(raws))
+	for i := len(raws) - 1; i >= 0; i-- {
+		vals = append(vals, raws[i])
+	}
+	if err := d.client.LPush(context.WithoutCancel(ctx), d.retryKey(channel), vals...).Err(); err != nil {
+		d.logger.Error("digest items lost", logging.Field{Key: "channel", Val: channel}, logging.Field{Key: "count", Val: len(raws)}, logging.Field{Key: "err", Val: err})
+	}
+}
+
+func (d *Digest) render(msgs []model.ScoredMessage) string {
+	sort.SliceStable(msgs, func(i, j int) bool { return msgs[i].Score > msgs[j].Score })
+	dropped := 0
+	if len(msgs) > d.cfg.MaxItems {
+		dropped = len(msgs) - d.cfg.MaxItems
+		msgs = msgs[:d.cfg.MaxItems]
+	}
+	groups := map[string][]model.ScoredMessage{}
+	for _, m := range msgs {
+		topic := d.topicOf(m)
+		groups[topic] = append(groups[topic], m)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "#### %s（%s）\n", d.cfg.Title, time.Now().Format("01-02 15:04"))
+	for _, topic := range append(append([]string{}, d.topics...), otherTopic) {
+		list := groups[topic]
+		if len(list) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n**%s**\n\n", topic)
+		for _, m := range list {
+			title := m.Title
+			if title == "" {
+				title = m.Content
+			}
+			if m.URL != "" {
+				fmt.Fprintf(&b, "- [%s](%s) 〔%s %d〕\n", title, m.URL, m.Source, m.Score)
+			} else {
+				fmt.Fprintf(&b, "- %s 〔%s %d〕\n", title, m.Source, m.Score)
+			}
+		}
+	}
+	if dropped > 0 {
+		fmt.Fprintf(&b, "\n> 另有 %d 条未列出\n", dropped)
+	}
+	return b.String()
+}
+
+func (d *Digest) topicOf(m model.ScoredMessage) string {
+	for _, r := range m.Reasons {
+		for _, t := range d.topics {
+			if model.ReasonName(r) == t {
+				return t
+			}
+		}
+	}
+	return otherTopic
+}
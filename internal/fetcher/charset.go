@@ -0,0 +1,89 @@
+package fetcher
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+var (
+	xmlDeclCharset = regexp.MustCompile(`(?i)^\s*<\?xml[^>]*encoding=["']([\w.:-]+)["']`)
+	metaCharset    = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?([\w.:-]+)`)
+	utf8BOM        = []byte{0xef, 0xbb, 0xbf}
+)
+
+// Decode transcodes body to UTF-8 and returns the charset it decided on. An
+// explicit override wins over the Content-Type header, which wins over an XML
+// declaration or HTML meta tag. Without any hint, bytes that are not valid
+// UTF-8 are assumed to be GB18030.
+func Decode(body []byte, contentType, override string) ([]byte, string, error) {
+	if bytes.HasPrefix(body, utf8BOM) {
+		return body[len(utf8BOM):], "utf-8", nil
+	}
+	label := detectCharset(body, contentType, override)
+	var enc encoding.Encoding
+	name := "utf-8"
+	if label != "" {
+		enc, name = charset.Lookup(label)
+		if enc == nil {
+			return body, label, fmt.Errorf("unsupported charset %q", label)
+		}
+	}
+	if name == "utf-8" {
+		if label != "" || utf8.Valid(body) {
+			return body, name, nil
+		}
+		enc, name = simplifiedchinese.GB18030, "gb18030"
+	}
+	if name == "gbk" {
+		enc, name = simplifiedchinese.GB18030, "gb18030"
+	}
+	out, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return body, name, err
+	}
+	return rewriteXMLDecl(out), name, nil
+}
+
+func detectCharset(body []byte, contentType, override string) string {
+	if override != "" {
+		return override
+	}
+	if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+		return params["charset"]
+	}
+	head := body
+	if len(head) > 2048 {
+		head = head[:2048]
+	}
+	if m := xmlDeclCharset.FindSubmatch(head); m != nil {
+		return string(m[1])
+	}
+	if m := metaCharset.FindSubmatch(head); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
+
+// rewriteXMLDecl points the XML declaration at UTF-8 so feed parsers do not
+// decode the already transcoded bytes a second time.
+func rewriteXMLDecl(body []byte) []byte {
+	loc := xmlDeclCharset.FindSubmatchIndex(body)
+	if loc == nil {
+		return body
+	}
+	if strings.EqualFold(string(body[loc[2]:loc[3]]), "utf-8") {
+		return body
+	}
+	out := make([]byte, 0, len(body))
+	out = append(out, body[:loc[2]]...)
+	out = append(out, "UTF-8"...)
+	return append(out, body[loc[3]:]...)
+}
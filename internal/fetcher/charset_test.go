@@ -0,0 +1,47 @@
+package fetcher
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func TestDecode(t *testing.T) {
+	gbk, err := simplifiedchinese.GBK.NewEncoder().String("行情快讯")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name        string
+		body        string
+		contentType string
+		override    string
+		want        string
+		charset     string
+	}{
+		{"bom", "\xef\xbb\xbf行情", "", "", "行情", "utf-8"},
+		{"plain utf-8", "行情", "", "", "行情", "utf-8"},
+		{"header", gbk, "text/html; charset=GBK", "", "行情快讯", "gb18030"},
+		{"override", gbk, "text/html; charset=utf-8", "gb2312", "行情快讯", "gb18030"},
+		{"sniffed", gbk, "", "", "行情快讯", "gb18030"},
+		{"meta", `<meta charset="gbk">` + gbk, "text/html", "", `<meta charset="gbk">行情快讯`, "gb18030"},
+		{"xml decl", `<?xml version="1.0" encoding="GB2312"?><t>` + gbk + "</t>", "", "", `<?xml version="1.0" encoding="UTF-8"?><t>行情快讯</t>`, "gb18030"},
+	}
+	for _, c := range cases {
+		out, name, err := Decode([]byte(c.body), c.contentType, c.override)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if string(out) != c.want || name != c.charset {
+			t.Errorf("%s: got %q (%s), want %q (%s)", c.name, out, name, c.want, c.charset)
+		}
+	}
+}
+
+func TestDecodeUnsupported(t *testing.T) {
+	_, name, err := Decode([]byte("x"), "text/plain; charset=x-unknown", "")
+	if err == nil || !strings.Contains(err.Error(), "x-unknown") || name != "x-unknown" {
+		t.Fatalf("got %q, %v", name, err)
+	}
+}
@@ -3,19 +3,43 @@ package fetcher
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"math/rand"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// RetryBudget caps the total time spent waiting between attempts.
+const RetryBudget = 10 * time.Second
+
 type Client struct {
-	httpClient *http.Client
-	retryOn    map[int]bool
+	httpClient  *http.Client
+	retryOn     map[int]bool
 	maxAttempts int
-	backoffMS int
-	multiplier float64
-	jitterMS int
+	backoffMS   int
+	multiplier  float64
+	jitterMS    int
+}
+
+// Error is returned when a request ultimately fails. It records how many
+// attempts were made, the last HTTP status seen (0 if none) and the total
+// time spent.
+type Error struct {
+	Attempts   int
+	LastStatus int
+	Elapsed    time.Duration
+	Err        error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v (attempts=%d status=%d elapsed=%s)", e.Err, e.Attempts, e.LastStatus, e.Elapsed.Round(time.Millisecond))
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
 }
 
 func New(timeout time.Duration, retryOnStatus []int, maxAttempts, backoffMS int, multiplier float64, jitterMS int) *Client {
@@ -33,40 +57,112 @@ func New(timeout time.Duration, retryOnStatus []int, maxAttempts, backoffMS int,
 		multiplier = 2
 	}
 	return &Client{
-		httpClient: &http.Client{Timeout: timeout},
-		retryOn:    m,
+		httpClient:  &http.Client{Timeout: timeout},
+		retryOn:     m,
 		maxAttempts: maxAttempts,
-		backoffMS: backoffMS,
-		multiplier: multiplier,
-		jitterMS: jitterMS,
+		backoffMS:   backoffMS,
+		multiplier:  multiplier,
+		jitterMS:    jitterMS,
 	}
 }
 
-func (c *Client) Do(ctx context.Context, req *http.Request) (int, []byte, error) {
+type Response struct {
+	Status   int
+	Header   http.Header
+	Body     []byte
+	Attempts int
+}
+
+func (r *Response) NotModified() bool {
+	return r != nil && r.Status == http.StatusNotModified
+}
+
+func (c *Client) Do(ctx context.Context, req *http.Request) (*Response, error) {
+	start := time.Now()
 	var lastErr error
+	var last *Response
+	var waited time.Duration
 	backoff := time.Duration(c.backoffMS) * time.Millisecond
+	fail := func(attempt int, err error) (*Response, error) {
+		e := &Error{Attempts: attempt, Elapsed: time.Since(start), Err: err}
+		if last != nil {
+			e.LastStatus = last.Status
+		}
+		return last, e
+	}
 	for attempt := 1; attempt <= c.maxAttempts; attempt++ {
-		resp, err := c.httpClient.Do(req.WithContext(ctx))
+		var retryAfter time.Duration
+		r := req.WithContext(ctx)
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return fail(attempt, err)
+			}
+			r.Body = body
+		}
+		resp, err := c.httpClient.Do(r)
 		if err != nil {
 			lastErr = err
 		} else {
 			body, readErr := io.ReadAll(resp.Body)
 			_ = resp.Body.Close()
+			last = &Response{Status: resp.StatusCode, Header: resp.Header, Body: body, Attempts: attempt}
 			if readErr != nil {
 				lastErr = readErr
-			} else if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-				return resp.StatusCode, body, nil
+			} else if resp.StatusCode >= 200 && resp.StatusCode < 300 || resp.StatusCode == http.StatusNotModified {
+				return last, nil
 			} else if c.retryOn[resp.StatusCode] {
 				lastErr = errors.New(resp.Status)
+				retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
 			} else {
-				return resp.StatusCode, body, errors.New(resp.Status)
+				return fail(attempt, errors.New(resp.Status))
 			}
 		}
-		if attempt < c.maxAttempts {
-			jitter := time.Duration(rand.Intn(c.jitterMS+1)) * time.Millisecond
-			time.Sleep(backoff + jitter)
-			backoff = time.Duration(float64(backoff) * c.multiplier)
+		if ctx.Err() != nil {
+			return fail(attempt, ctx.Err())
+		}
+		if attempt == c.maxAttempts {
+			return fail(attempt, lastErr)
+		}
+		wait := backoff + time.Duration(rand.Intn(c.jitterMS+1))*time.Millisecond
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		if waited+wait > RetryBudget {
+			if retryAfter > 0 {
+				return fail(attempt, fmt.Errorf("%w: retry-after %s exceeds retry budget", lastErr, retryAfter))
+			}
+			wait = RetryBudget - waited
+		}
+		waited += wait
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fail(attempt, ctx.Err())
+		case <-timer.C:
+		}
+		backoff = time.Duration(float64(backoff) * c.multiplier)
+	}
+	return fail(c.maxAttempts, lastErr)
+}
+
+// parseRetryAfter accepts both delta-seconds and HTTP-date forms.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
 		}
 	}
-	return 0, nil, lastErr
+	return 0
 }
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"realtime-message/internal/logging"
+)
+
+var (
+	counters = expvar.NewMap("counters")
+	gauges   = expvar.NewMap("gauges")
+	gaugesMu sync.Mutex
+)
+
+// Name joins a metric name with its label values, e.g. "push_sent.dingding".
+func Name(parts ...string) string {
+	return strings.Join(parts, ".")
+}
+
+func Inc(name string) {
+	counters.Add(name, 1)
+}
+
+func Add(name string, delta int64) {
+	counters.Add(name, delta)
+}
+
+func Set(name string, v int64) {
+	gaugesMu.Lock()
+	defer gaugesMu.Unlock()
+	g, ok := gauges.Get(name).(*expvar.Int)
+	if !ok {
+		g = new(expvar.Int)
+		gauges.Set(name, g)
+	}
+	g.Set(v)
+}
+
+func Serve(ctx context.Context, addr string, logger *logging.Logger) {
+	if addr == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", expvar.Handler())
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	go func() {
+		logger.Info("metrics listening", logging.Field{Key: "addr", Val: addr})
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("metrics server failed", logging.Field{Key: "err", Val: err})
+		}
+	}()
+}
@@ -1,6 +1,9 @@
 package model
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 type Message struct {
 	ID      string
@@ -9,6 +12,9 @@ type Message struct {
 	URL     string
 	Time    time.Time
 	Source  string
+	// TimeUnknown marks a Time that could not be parsed and was set to the
+	// fetch time instead.
+	TimeUnknown bool
 }
 
 type ScoredMessage struct {
@@ -16,3 +22,21 @@ type ScoredMessage struct {
 	Score   int
 	Reasons []string
 }
+
+// ReasonName strips the detail suffix from a reason, so "货币政策:降息" and
+// "corroborated:2" yield "货币政策" and "corroborated".
+func ReasonName(reason string) string {
+	if i := strings.IndexByte(reason, ':'); i >= 0 {
+		return reason[:i]
+	}
+	return reason
+}
+
+func (m ScoredMessage) HasReason(name string) bool {
+	for _, r := range m.Reasons {
+		if ReasonName(r) == name {
+			return true
+		}
+	}
+	return false
+}
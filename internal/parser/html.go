@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"realtime-message/internal/config"
+	"realtime-message/internal/model"
+)
+
+// ParseHTML extracts one message per element matching cfg.HTML.Item. Field
+// selectors are evaluated relative to the item and may end in "@attr" to read
+// an attribute instead of the text; a bare "@attr" reads the item itself.
+func ParseHTML(source, pageURL string, body []byte, cfg config.ParserConfig) ([]model.Message, error) {
+	if cfg.HTML.Item == "" {
+		return nil, fmt.Errorf("parser.html.item selector required")
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	base, _ := url.Parse(pageURL)
+	fields := cfg.HTML.Fields
+	tp := newTimeParser(cfg.Time)
+	var msgs []model.Message
+	doc.Find(cfg.HTML.Item).Each(func(_ int, item *goquery.Selection) {
+		m := model.Message{
+			Source:  source,
+			Title:   selectField(item, fields["title"]),
+			Content: selectField(item, fields["content"]),
+			ID:      selectField(item, fields["id"]),
+			URL:     resolveURL(base, selectField(item, fields["url"])),
+			Time:    tp.parseString(selectField(item, fields["time"])),
+		}
+		settleTime(&m)
+		if m.Title == "" && m.Content == "" {
+			return
+		}
+		msgs = append(msgs, m)
+	})
+	return msgs, nil
+}
+
+func selectField(item *goquery.Selection, spec string) string {
+	if spec == "" {
+		return ""
+	}
+	selector, attr, hasAttr := strings.Cut(spec, "@")
+	sel := item
+	if strings.TrimSpace(selector) != "" {
+		sel = item.Find(strings.TrimSpace(selector)).First()
+	}
+	if sel.Length() == 0 {
+		return ""
+	}
+	if hasAttr {
+		v, _ := sel.Attr(strings.TrimSpace(attr))
+		return strings.TrimSpace(v)
+	}
+	return strings.Join(strings.Fields(sel.Text()), " ")
+}
+
+func resolveURL(base *url.URL, ref string) string {
+	if ref == "" || base == nil {
+		return ref
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
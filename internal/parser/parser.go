@@ -27,6 +27,7 @@ func ParseJSON(source string, body []byte, cfg config.ParserConfig) ([]model.Mes
 	if items == nil {
 		return nil, fmt.Errorf("no list found in json")
 	}
+	tp := newTimeParser(cfg.Time)
 	msgs := make([]model.Message, 0, len(items))
 	for _, item := range items {
 		obj, ok := item.(map[string]any)
@@ -35,13 +36,11 @@ func ParseJSON(source string, body []byte, cfg config.ParserConfig) ([]model.Mes
 		}
 		m := model.Message{Source: source}
 		if strings.ToLower(cfg.Mode) == "mapping" {
-			m = applyMapping(obj, m, cfg.Mapping)
+			m = applyMapping(obj, m, cfg.Mapping, tp)
 		} else {
-			m = applyAuto(obj, m)
-		}
-		if m.Time.IsZero() {
-			m.Time = time.Now()
+			m = applyAuto(obj, m, tp)
 		}
+		settleTime(&m)
 		if m.Title == "" && m.Content == "" {
 			continue
 		}
@@ -50,44 +49,65 @@ func ParseJSON(source string, body []byte, cfg config.ParserConfig) ([]model.Mes
 	return msgs, nil
 }
 
-func ParseRSS(source string, body []byte) ([]model.Message, error) {
+// JSONValue returns the scalar at a dotted path of a JSON document, or "".
+func JSONValue(body []byte, path string) string {
+	var data map[string]any
+	if err := json.Unmarshal(body, &data); err != nil {
+		return ""
+	}
+	return getString(data, path)
+}
+
+// ParseRSS prefers gofeed's parsed dates; when the source sets its own
+// layouts or timezone, the raw date strings are tried with those first.
+func ParseRSS(source string, body []byte, cfg config.ParserConfig) ([]model.Message, error) {
 	fp := gofeed.NewParser()
 	feed, err := fp.ParseString(string(body))
 	if err != nil {
 		return nil, err
 	}
+	tp := newTimeParser(cfg.Time)
+	custom := len(cfg.Time.Layouts) > 0 || cfg.Time.Timezone != ""
 	msgs := make([]model.Message, 0, len(feed.Items))
 	for _, item := range feed.Items {
-		t := time.Now()
-		if item.PublishedParsed != nil {
+		var t time.Time
+		if custom {
+			t = tp.parseString(firstNonEmpty(item.Published, item.Updated))
+		}
+		if t.IsZero() && item.PublishedParsed != nil {
 			t = *item.PublishedParsed
 		}
-		msgs = append(msgs, model.Message{
+		if t.IsZero() && item.UpdatedParsed != nil {
+			t = *item.UpdatedParsed
+		}
+		if t.IsZero() {
+			t = tp.parseString(firstNonEmpty(item.Published, item.Updated))
+		}
+		m := model.Message{
 			ID:      item.GUID,
 			Title:   item.Title,
 			Content: item.Description,
 			URL:     item.Link,
 			Time:    t,
 			Source:  source,
-		})
+		}
+		settleTime(&m)
+		msgs = append(msgs, m)
 	}
 	return msgs, nil
 }
 
-func applyMapping(obj map[string]any, msg model.Message, mapping config.MappingConfig) model.Message {
+func applyMapping(obj map[string]any, msg model.Message, mapping config.MappingConfig, tp *timeParser) model.Message {
 	fields := mapping.Fields
-	if fields == nil {
-		fields = map[string]string{}
-	}
-	msg.Title = getString(obj, fields["title"])
-	msg.Content = getString(obj, fields["content"])
-	msg.URL = getString(obj, fields["url"])
-	msg.ID = getString(obj, fields["id"])
-	msg.Time = parseTime(getAny(obj, fields["time"]))
+	msg.Title = fieldString(obj, fields["title"])
+	msg.Content = fieldString(obj, fields["content"])
+	msg.URL = fieldString(obj, fields["url"])
+	msg.ID = fieldString(obj, fields["id"])
+	msg.Time = fieldTime(obj, fields["time"], tp)
 	return msg
 }
 
-func applyAuto(obj map[string]any, msg model.Message) model.Message {
+func applyAuto(obj map[string]any, msg model.Message, tp *timeParser) model.Message {
 	msg.Title = firstNonEmpty(
 		getString(obj, "title"),
 		getString(obj, "headline"),
@@ -114,7 +134,7 @@ func applyAuto(obj map[string]any, msg model.Message) model.Message {
 		getString(obj, "guid"),
 		getString(obj, "news_id"),
 	)
-	msg.Time = parseTime(firstNonNil(
+	msg.Time = tp.parse(firstNonNil(
 		getAny(obj, "time"),
 		getAny(obj, "timestamp"),
 		getAny(obj, "published_at"),
@@ -147,21 +167,26 @@ func autoFindList(data any) []any {
 	return nil
 }
 
+// findByPath returns the list at path: the array it points to, or every
+// value matched when the path has wildcards or filters.
 func findByPath(data any, path string) []any {
-	parts := strings.Split(path, ".")
-	cur := data
-	for _, p := range parts {
-		if p == "" {
-			continue
+	steps := cachedPath(path)
+	vals := evalPath(data, steps)
+	if multi(steps) {
+		if len(vals) == 1 {
+			if arr, ok := vals[0].([]any); ok {
+				return arr
+			}
 		}
-		m, ok := cur.(map[string]any)
-		if !ok {
-			return nil
+		if vals == nil {
+			return []any{}
 		}
-		cur = m[p]
+		return vals
 	}
-	if arr, ok := cur.([]any); ok {
-		return arr
+	if len(vals) == 1 {
+		if arr, ok := vals[0].([]any); ok {
+			return arr
+		}
 	}
 	return nil
 }
@@ -217,7 +242,7 @@ func getAny(obj map[string]any, key string) any {
 	if key == "" {
 		return nil
 	}
-	if strings.Contains(key, ".") {
+	if strings.ContainsAny(key, ".[*$") {
 		return getAnyByPath(obj, key)
 	}
 	return obj[key]
@@ -239,42 +264,21 @@ func getString(obj map[string]any, key string) string {
 	}
 }
 
+// getAnyByPath returns the value at path, or all matched values as a list
+// when the path has wildcards or filters.
 func getAnyByPath(obj map[string]any, path string) any {
-	parts := strings.Split(path, ".")
-	var cur any = obj
-	for _, p := range parts {
-		if p == "" {
-			continue
-		}
-		m, ok := cur.(map[string]any)
-		if !ok {
+	steps := cachedPath(path)
+	vals := evalPath(obj, steps)
+	if multi(steps) {
+		if len(vals) == 0 {
 			return nil
 		}
-		cur = m[p]
+		return vals
 	}
-	return cur
-}
-
-func parseTime(val any) time.Time {
-	switch v := val.(type) {
-	case time.Time:
-		return v
-	case json.Number:
-		if i, err := v.Int64(); err == nil {
-			return fromUnix(i)
-		}
-	case float64:
-		return fromUnix(int64(v))
-	case int64:
-		return fromUnix(v)
-	case int:
-		return fromUnix(int64(v))
-	case string:
-		return parseTimeString(v)
-	default:
-		return time.Time{}
+	if len(vals) == 0 {
+		return nil
 	}
-	return time.Time{}
+	return vals[0]
 }
 
 func fromUnix(v int64) time.Time {
@@ -284,27 +288,6 @@ func fromUnix(v int64) time.Time {
 	return time.Unix(v, 0)
 }
 
-func parseTimeString(s string) time.Time {
-	s = strings.TrimSpace(s)
-	if s == "" {
-		return time.Time{}
-	}
-	for _, layout := range []string{
-		time.RFC3339,
-		"2006-01-02 15:04:05",
-		"2006-01-02 15:04",
-		"2006-01-02",
-	} {
-		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
-			return t
-		}
-	}
-	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
-		return fromUnix(i)
-	}
-	return time.Time{}
-}
-
 func firstNonEmpty(values ...string) string {
 	for _, v := range values {
 		if strings.TrimSpace(v) != "" {
@@ -0,0 +1,285 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var pathCache sync.Map
+
+// cachedPath compiles path once; invalid paths match nothing.
+func cachedPath(path string) []step {
+	if v, ok := pathCache.Load(path); ok {
+		return v.([]step)
+	}
+	steps, err := compilePath(path)
+	if err != nil {
+		steps = []step{{invalid: true}}
+	}
+	pathCache.Store(path, steps)
+	return steps
+}
+
+// step is one segment of a path: a key, an index, a wildcard or a filter.
+type step struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+	filter   *predicate
+	invalid  bool
+}
+
+// predicate is a [?(@.key op value)] filter; an empty op tests presence.
+type predicate struct {
+	path  string
+	op    string
+	value string
+}
+
+// multi reports whether a path can yield several values.
+func multi(steps []step) bool {
+	for _, s := range steps {
+		if s.wildcard || s.filter != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// compilePath parses paths like "data[0].list", "tags[*].name" or
+// "items[?(@.type == 'telegraph')]". A leading "$" is optional.
+func compilePath(path string) ([]step, error) {
+	p := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	var steps []step
+	for i := 0; i < len(p); {
+		switch p[i] {
+		case '.':
+			i++
+		case '[':
+			end := closingBracket(p, i)
+			if end < 0 {
+				return nil, fmt.Errorf("path %q: unclosed [", path)
+			}
+			st, err := parseBracket(p[i+1 : end])
+			if err != nil {
+				return nil, fmt.Errorf("path %q: %w", path, err)
+			}
+			steps = append(steps, st)
+			i = end + 1
+		default:
+			j := i
+			for j < len(p) && p[j] != '.' && p[j] != '[' {
+				j++
+			}
+			key := p[i:j]
+			if key == "*" {
+				steps = append(steps, step{wildcard: true})
+			} else {
+				steps = append(steps, step{key: key})
+			}
+			i = j
+		}
+	}
+	return steps, nil
+}
+
+func closingBracket(p string, open int) int {
+	var quote byte
+	depth := 0
+	for i := open; i < len(p); i++ {
+		c := p[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func parseBracket(in string) (step, error) {
+	in = strings.TrimSpace(in)
+	switch {
+	case in == "*":
+		return step{wildcard: true}, nil
+	case strings.HasPrefix(in, "?"):
+		pred, err := parsePredicate(in[1:])
+		if err != nil {
+			return step{}, err
+		}
+		return step{filter: pred}, nil
+	case len(in) >= 2 && (in[0] == '\'' || in[0] == '"') && in[len(in)-1] == in[0]:
+		return step{key: in[1 : len(in)-1]}, nil
+	}
+	n, err := strconv.Atoi(in)
+	if err != nil {
+		return step{}, fmt.Errorf("invalid index %q", in)
+	}
+	return step{index: n, isIndex: true}, nil
+}
+
+func parsePredicate(in string) (*predicate, error) {
+	in = strings.TrimSpace(in)
+	if strings.HasPrefix(in, "(") && strings.HasSuffix(in, ")") {
+		in = strings.TrimSpace(in[1 : len(in)-1])
+	}
+	if i, op := findOperator(in); op != "" {
+		value := strings.TrimSpace(in[i+len(op):])
+		if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		return &predicate{path: trimAt(in[:i]), op: op, value: value}, nil
+	}
+	if in == "" {
+		return nil, fmt.Errorf("empty filter")
+	}
+	return &predicate{path: trimAt(in)}, nil
+}
+
+// findOperator returns the first comparison operator outside quotes.
+func findOperator(in string) (int, string) {
+	var quote byte
+	for i := 0; i < len(in); i++ {
+		c := in[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		default:
+			for _, op := range []string{"==", "!=", ">=", "<=", ">", "<"} {
+				if strings.HasPrefix(in[i:], op) {
+					return i, op
+				}
+			}
+		}
+	}
+	return -1, ""
+}
+
+func trimAt(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "@")
+	return strings.TrimPrefix(s, ".")
+}
+
+// evalPath returns every value matching steps in data.
+func evalPath(data any, steps []step) []any {
+	cur := []any{data}
+	for _, st := range steps {
+		if st.invalid {
+			return nil
+		}
+		var next []any
+		for _, v := range cur {
+			switch x := v.(type) {
+			case map[string]any:
+				switch {
+				case st.wildcard:
+					keys := make([]string, 0, len(x))
+					for k := range x {
+						keys = append(keys, k)
+					}
+					sort.Strings(keys)
+					for _, k := range keys {
+						next = append(next, x[k])
+					}
+				case st.filter != nil:
+					if st.filter.match(x) {
+						next = append(next, x)
+					}
+				case !st.isIndex:
+					if val, ok := x[st.key]; ok {
+						next = append(next, val)
+					}
+				}
+			case []any:
+				switch {
+				case st.wildcard:
+					next = append(next, x...)
+				case st.filter != nil:
+					for _, item := range x {
+						if st.filter.match(item) {
+							next = append(next, item)
+						}
+					}
+				case st.isIndex:
+					i := st.index
+					if i < 0 {
+						i += len(x)
+					}
+					if i >= 0 && i < len(x) {
+						next = append(next, x[i])
+					}
+				}
+			}
+		}
+		cur = next
+	}
+	return cur
+}
+
+func (p *predicate) match(item any) bool {
+	vals := evalPath(item, cachedPath(p.path))
+	if len(vals) == 0 {
+		return false
+	}
+	if p.op == "" {
+		return vals[0] != nil && vals[0] != false
+	}
+	got := scalarString(vals[0])
+	if a, err1 := strconv.ParseFloat(got, 64); err1 == nil {
+		if b, err2 := strconv.ParseFloat(p.value, 64); err2 == nil {
+			return compare(p.op, a-b)
+		}
+	}
+	return compare(p.op, float64(strings.Compare(got, p.value)))
+}
+
+func compare(op string, diff float64) bool {
+	switch op {
+	case "==":
+		return diff == 0
+	case "!=":
+		return diff != 0
+	case ">":
+		return diff > 0
+	case "<":
+		return diff < 0
+	case ">=":
+		return diff >= 0
+	default:
+		return diff <= 0
+	}
+}
+
+func scalarString(v any) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(x)
+	}
+}
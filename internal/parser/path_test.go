@@ -0,0 +1,37 @@
+package parser
+
+import "testing"
+
+func TestParsePredicateQuotedOperator(t *testing.T) {
+	cases := []struct {
+		in    string
+		path  string
+		op    string
+		value string
+	}{
+		{`(@.k != 'a==b')`, "k", "!=", "a==b"},
+		{`(@.k == "x<y")`, "k", "==", "x<y"},
+		{`(@.n >= 3)`, "n", ">=", "3"},
+		{`(@.url)`, "url", "", ""},
+	}
+	for _, c := range cases {
+		p, err := parsePredicate(c.in)
+		if err != nil {
+			t.Fatalf("%s: %v", c.in, err)
+		}
+		if p.path != c.path || p.op != c.op || p.value != c.value {
+			t.Errorf("%s: got path=%q op=%q value=%q", c.in, p.path, p.op, p.value)
+		}
+	}
+}
+
+func TestFindByPathQuotedFilter(t *testing.T) {
+	data := map[string]any{"list": []any{
+		map[string]any{"k": "a==b", "title": "skip"},
+		map[string]any{"k": "c", "title": "keep"},
+	}}
+	got := findByPath(data, `list[?(@.k != 'a==b')]`)
+	if len(got) != 1 || got[0].(map[string]any)["title"] != "keep" {
+		t.Fatalf("got %v", got)
+	}
+}
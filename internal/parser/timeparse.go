@@ -0,0 +1,194 @@
+package parser
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"realtime-message/internal/config"
+	"realtime-message/internal/model"
+)
+
+var (
+	fullLayouts = []string{
+		time.RFC3339Nano,
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05",
+		"2006-01-02 15:04",
+		"2006-01-02",
+		"2006/1/2 15:04:05",
+		"2006/1/2 15:04",
+		"2006/1/2",
+		"2006年1月2日 15:04:05",
+		"2006年1月2日 15:04",
+		"2006年1月2日15:04",
+		"2006年1月2日",
+		time.RFC1123Z,
+		time.RFC1123,
+		time.RFC822Z,
+		time.RFC822,
+	}
+	// yearless layouts take the year that puts the time closest before now
+	yearlessLayouts = []string{
+		"01-02 15:04:05",
+		"01-02 15:04",
+		"1月2日 15:04:05",
+		"1月2日 15:04",
+		"1月2日15:04",
+		"01/02 15:04",
+		"1月2日",
+	}
+	clockLayouts = []string{"15:04:05", "15:04"}
+
+	relativeAgo = regexp.MustCompile(`^(\d+)\s*(秒|分钟|分|小时|天|seconds?|secs?|minutes?|mins?|hours?|days?)\s*(前|ago)$`)
+	relativeDay = regexp.MustCompile(`^(今天|昨天|前天)\s*(\d{1,2}:\d{2}(?::\d{2})?)?$`)
+)
+
+// timeParser parses message times for one source: its own layouts first,
+// then the built-in ones, all in the source timezone.
+type timeParser struct {
+	layouts []string
+	loc     *time.Location
+	now     time.Time
+}
+
+func newTimeParser(cfg config.TimeConfig) *timeParser {
+	loc := time.Local
+	if cfg.Timezone != "" {
+		if l, err := time.LoadLocation(cfg.Timezone); err == nil {
+			loc = l
+		}
+	}
+	return &timeParser{layouts: cfg.Layouts, loc: loc, now: time.Now().In(loc)}
+}
+
+func (p *timeParser) parse(val any) time.Time {
+	switch v := val.(type) {
+	case time.Time:
+		return v
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return fromUnix(i)
+		}
+	case float64:
+		return fromUnix(int64(v))
+	case int64:
+		return fromUnix(v)
+	case int:
+		return fromUnix(int64(v))
+	case string:
+		return p.parseString(v)
+	}
+	return time.Time{}
+}
+
+func (p *timeParser) parseString(s string) time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}
+	}
+	for _, layout := range p.layouts {
+		if t, err := time.ParseInLocation(layout, s, p.loc); err == nil {
+			if t.Year() == 0 {
+				return p.complete(t, layoutHasDate(layout))
+			}
+			return t
+		}
+	}
+	for _, layout := range fullLayouts {
+		if t, err := time.ParseInLocation(layout, s, p.loc); err == nil {
+			return t
+		}
+	}
+	for _, layout := range yearlessLayouts {
+		if t, err := time.ParseInLocation(layout, s, p.loc); err == nil {
+			return p.complete(t, true)
+		}
+	}
+	for _, layout := range clockLayouts {
+		if t, err := time.ParseInLocation(layout, s, p.loc); err == nil {
+			return p.complete(t, false)
+		}
+	}
+	if t, ok := p.relative(s); ok {
+		return t
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return fromUnix(i)
+	}
+	return time.Time{}
+}
+
+// complete fills in the missing year (or whole date) of t. A result more
+// than a day (or, for bare clock times, ten minutes) ahead of now is taken
+// to be from the previous year (or day).
+func (p *timeParser) complete(t time.Time, hasDate bool) time.Time {
+	now := p.now
+	if hasDate {
+		out := time.Date(now.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, p.loc)
+		if out.After(now.Add(24 * time.Hour)) {
+			out = out.AddDate(-1, 0, 0)
+		}
+		return out
+	}
+	out := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), t.Second(), 0, p.loc)
+	if out.After(now.Add(10 * time.Minute)) {
+		out = out.AddDate(0, 0, -1)
+	}
+	return out
+}
+
+// relative handles "刚刚", "5分钟前", "3 hours ago" and "昨天 15:04".
+func (p *timeParser) relative(s string) (time.Time, bool) {
+	if s == "刚刚" || strings.EqualFold(s, "just now") {
+		return p.now, true
+	}
+	lower := strings.ToLower(s)
+	if m := relativeAgo.FindStringSubmatch(lower); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		unit := time.Second
+		switch {
+		case m[2] == "分钟" || m[2] == "分" || strings.HasPrefix(m[2], "min"):
+			unit = time.Minute
+		case m[2] == "小时" || strings.HasPrefix(m[2], "hour"):
+			unit = time.Hour
+		case m[2] == "天" || strings.HasPrefix(m[2], "day"):
+			unit = 24 * time.Hour
+		}
+		return p.now.Add(-time.Duration(n) * unit), true
+	}
+	if m := relativeDay.FindStringSubmatch(s); m != nil {
+		days := map[string]int{"今天": 0, "昨天": 1, "前天": 2}[m[1]]
+		day := p.now.AddDate(0, 0, -days)
+		h, min, sec := 0, 0, 0
+		if m[2] != "" {
+			parts := strings.Split(m[2], ":")
+			h, _ = strconv.Atoi(parts[0])
+			min, _ = strconv.Atoi(parts[1])
+			if len(parts) > 2 {
+				sec, _ = strconv.Atoi(parts[2])
+			}
+		}
+		return time.Date(day.Year(), day.Month(), day.Day(), h, min, sec, 0, p.loc), true
+	}
+	return time.Time{}, false
+}
+
+// layoutHasDate reports whether a layout has month or day fields once the
+// clock and year fields are removed.
+func layoutHasDate(layout string) bool {
+	rest := strings.NewReplacer("2006", "", "15", "", "03", "", "04", "", "05", "", "06", "").Replace(layout)
+	return strings.ContainsAny(rest, "12") || strings.Contains(rest, "Jan")
+}
+
+// settleTime flags messages without a usable time. They keep the fetch time
+// for display but are left out of market-hours scoring, the high-water mark
+// and time-based dedupe keys.
+func settleTime(m *model.Message) {
+	if m.Time.IsZero() {
+		m.Time = time.Now()
+		m.TimeUnknown = true
+	}
+}
@@ -0,0 +1,51 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"realtime-message/internal/config"
+)
+
+func TestParseString(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	at := func(y int, mo time.Month, d, h, mi, s int) time.Time { return time.Date(y, mo, d, h, mi, s, 0, loc) }
+	p := newTimeParser(config.TimeConfig{Layouts: []string{"Jan 2 15:04"}})
+	p.loc = loc
+	p.now = at(2026, 10, 17, 10, 0, 0)
+	cases := []struct {
+		in   string
+		want time.Time
+	}{
+		{"2026-10-16 09:30:00", at(2026, 10, 16, 9, 30, 0)},
+		{"2026/10/16 09:30", at(2026, 10, 16, 9, 30, 0)},
+		{"2026年10月16日 09:30", at(2026, 10, 16, 9, 30, 0)},
+		{"2026年10月16日09:30", at(2026, 10, 16, 9, 30, 0)},
+		{"10月16日 09:30", at(2026, 10, 16, 9, 30, 0)},
+		{"10月16日", at(2026, 10, 16, 0, 0, 0)},
+		{"12-31 23:00", at(2025, 12, 31, 23, 0, 0)},
+		{"Oct 16 09:30", at(2026, 10, 16, 9, 30, 0)},
+		{"09:30", at(2026, 10, 17, 9, 30, 0)},
+		{"10:05", at(2026, 10, 17, 10, 5, 0)},
+		{"10:30", at(2026, 10, 16, 10, 30, 0)},
+		{"刚刚", at(2026, 10, 17, 10, 0, 0)},
+		{"5分钟前", at(2026, 10, 17, 9, 55, 0)},
+		{"30秒前", at(2026, 10, 17, 9, 59, 30)},
+		{"2小时前", at(2026, 10, 17, 8, 0, 0)},
+		{"1天前", at(2026, 10, 16, 10, 0, 0)},
+		{"3 hours ago", at(2026, 10, 17, 7, 0, 0)},
+		{"10 mins ago", at(2026, 10, 17, 9, 50, 0)},
+		{"昨天 15:04", at(2026, 10, 16, 15, 4, 0)},
+		{"前天", at(2026, 10, 15, 0, 0, 0)},
+		{"今天 08:00:05", at(2026, 10, 17, 8, 0, 5)},
+		{"1760666400", time.Unix(1760666400, 0)},
+		{"1760666400000", time.UnixMilli(1760666400000)},
+		{"", time.Time{}},
+		{"not a time", time.Time{}},
+	}
+	for _, c := range cases {
+		if got := p.parseString(c.in); !got.Equal(c.want) {
+			t.Errorf("%q: got %v, want %v", c.in, got, c.want)
+		}
+	}
+}
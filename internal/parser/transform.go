@@ -0,0 +1,100 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"realtime-message/internal/config"
+)
+
+var regexCache sync.Map
+
+// fieldString reads f.Path from obj and applies the field's transforms.
+func fieldString(obj map[string]any, f config.FieldConfig) string {
+	var s string
+	switch v := getAny(obj, f.Path).(type) {
+	case []any:
+		sep := f.Join
+		if sep == "" {
+			sep = ","
+		}
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			if str := strings.TrimSpace(scalarString(item)); str != "" {
+				if _, ok := item.(map[string]any); !ok {
+					parts = append(parts, str)
+				}
+			}
+		}
+		s = strings.Join(parts, sep)
+	case bool:
+		s = scalarString(v)
+	default:
+		s = getString(obj, f.Path)
+	}
+	if f.StripHTML && s != "" {
+		s = stripHTML(s)
+	}
+	if f.Regex != "" && s != "" {
+		s = extract(f.Regex, s)
+	}
+	if f.Prefix != "" && s != "" && !strings.Contains(s, "://") {
+		s = f.Prefix + s
+	}
+	if s == "" {
+		s = f.Default
+	}
+	return s
+}
+
+// fieldTime parses the time field: with a layout, or with any transform,
+// the transformed string is parsed; otherwise the raw value is.
+func fieldTime(obj map[string]any, f config.FieldConfig, tp *timeParser) time.Time {
+	if f.Layout == "" && f.Regex == "" && !f.StripHTML && f.Prefix == "" && f.Default == "" {
+		return tp.parse(getAny(obj, f.Path))
+	}
+	s := fieldString(obj, f)
+	if f.Layout != "" {
+		if t, err := time.ParseInLocation(f.Layout, strings.TrimSpace(s), tp.loc); err == nil {
+			if t.Year() == 0 {
+				return tp.complete(t, layoutHasDate(f.Layout))
+			}
+			return t
+		}
+		return time.Time{}
+	}
+	return tp.parseString(s)
+}
+
+// extract returns the first capture group of expr in s, or the whole match.
+func extract(expr, s string) string {
+	v, ok := regexCache.Load(expr)
+	if !ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return ""
+		}
+		v, _ = regexCache.LoadOrStore(expr, re)
+	}
+	m := v.(*regexp.Regexp).FindStringSubmatch(s)
+	switch {
+	case m == nil:
+		return ""
+	case len(m) > 1:
+		return m[1]
+	default:
+		return m[0]
+	}
+}
+
+func stripHTML(s string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(s))
+	if err != nil {
+		return s
+	}
+	return strings.Join(strings.Fields(doc.Text()), " ")
+}
@@ -1,25 +1,27 @@
 package push
 
 import (
-	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"net/http"
 	"net/url"
 	"strings"
 	"time"
+
+	"realtime-message/internal/model"
 )
 
 type DingTalk struct {
-	Webhook   string
-	Secret    string
-	MsgType   string
-	Title     string
-	Timeout   time.Duration
-	Template  string
+	Channel  string
+	Webhook  string
+	Secret   string
+	MsgType  string
+	Title    string
+	Timeout  time.Duration
+	Template string
 }
 
 type Response struct {
@@ -27,7 +29,22 @@ type Response struct {
 	ErrMsg  string `json:"errmsg"`
 }
 
-func (d *DingTalk) SendMarkdown(content string) error {
+func (d *DingTalk) Name() string {
+	return d.Channel
+}
+
+func (d *DingTalk) Render(msg model.ScoredMessage) string {
+	return Render(d.Template, msg)
+}
+
+func (d *DingTalk) Send(ctx context.Context, msg model.ScoredMessage, body string) error {
+	if strings.ToLower(d.MsgType) == "text" {
+		return d.SendText(ctx, body)
+	}
+	return d.SendMarkdown(ctx, body)
+}
+
+func (d *DingTalk) SendMarkdown(ctx context.Context, content string) error {
 	payload := map[string]any{
 		"msgtype": "markdown",
 		"markdown": map[string]string{
@@ -35,32 +52,37 @@ func (d *DingTalk) SendMarkdown(content string) error {
 			"text":  content,
 		},
 	}
-	return d.send(payload)
+	return d.send(ctx, payload)
 }
 
-func (d *DingTalk) SendText(content string) error {
+func (d *DingTalk) SendText(ctx context.Context, content string) error {
 	payload := map[string]any{
 		"msgtype": "text",
 		"text": map[string]string{
 			"content": content,
 		},
 	}
-	return d.send(payload)
+	return d.send(ctx, payload)
 }
 
-func (d *DingTalk) send(payload map[string]any) error {
-	ts := fmt.Sprintf("%d", time.Now().UnixMilli())
-	sign := sign(ts, d.Secret)
-	endpoint := fmt.Sprintf("%s&timestamp=%s&sign=%s", d.Webhook, ts, sign)
-	buf, _ := json.Marshal(payload)
-	client := &http.Client{Timeout: d.Timeout}
-	resp, err := client.Post(endpoint, "application/json", bytes.NewReader(buf))
+func (d *DingTalk) send(ctx context.Context, payload map[string]any) error {
+	endpoint := d.Webhook
+	if d.Secret != "" {
+		ts := fmt.Sprintf("%d", time.Now().UnixMilli())
+		sign := sign(ts, d.Secret)
+		endpoint = fmt.Sprintf("%s&timestamp=%s&sign=%s", d.Webhook, ts, sign)
+	}
+	status, body, err := postJSON(ctx, d.Timeout, endpoint, nil, payload)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	if status < 200 || status >= 300 {
+		return fmt.Errorf("dingding status %d", status)
+	}
 	var r Response
-	_ = json.NewDecoder(resp.Body).Decode(&r)
+	if err := json.Unmarshal(body, &r); err != nil {
+		return fmt.Errorf("dingding response: %w", err)
+	}
 	if r.ErrCode != 0 {
 		return fmt.Errorf("dingding error %d: %s", r.ErrCode, r.ErrMsg)
 	}
@@ -0,0 +1,81 @@
+package push
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"realtime-message/internal/model"
+)
+
+type Feishu struct {
+	Channel  string
+	Webhook  string
+	Secret   string
+	MsgType  string
+	Title    string
+	Timeout  time.Duration
+	Template string
+}
+
+type feishuResponse struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+func (f *Feishu) Name() string {
+	return f.Channel
+}
+
+func (f *Feishu) Render(msg model.ScoredMessage) string {
+	return Render(f.Template, msg)
+}
+
+func (f *Feishu) Send(ctx context.Context, msg model.ScoredMessage, body string) error {
+	payload := map[string]any{}
+	if strings.ToLower(f.MsgType) == "text" {
+		payload["msg_type"] = "text"
+		payload["content"] = map[string]string{"text": body}
+	} else {
+		payload["msg_type"] = "interactive"
+		payload["card"] = map[string]any{
+			"header": map[string]any{
+				"title": map[string]string{"tag": "plain_text", "content": f.Title},
+			},
+			"elements": []any{
+				map[string]string{"tag": "markdown", "content": body},
+			},
+		}
+	}
+	if f.Secret != "" {
+		ts := fmt.Sprintf("%d", time.Now().Unix())
+		payload["timestamp"] = ts
+		payload["sign"] = feishuSign(ts, f.Secret)
+	}
+	status, resp, err := postJSON(ctx, f.Timeout, f.Webhook, nil, payload)
+	if err != nil {
+		return err
+	}
+	if status < 200 || status >= 300 {
+		return fmt.Errorf("feishu status %d", status)
+	}
+	var r feishuResponse
+	if err := json.Unmarshal(resp, &r); err != nil {
+		return fmt.Errorf("feishu response: %w", err)
+	}
+	if r.Code != 0 {
+		return fmt.Errorf("feishu error %d: %s", r.Code, r.Msg)
+	}
+	return nil
+}
+
+// Feishu signs with "timestamp\nsecret" as the HMAC key over an empty message.
+func feishuSign(timestamp, secret string) string {
+	h := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,288 @@
+package push
+
+import (
+	"container/heap"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"realtime-message/internal/logging"
+	"realtime-message/internal/metrics"
+	"realtime-message/internal/model"
+)
+
+type outboxItem struct {
+	msg      model.ScoredMessage
+	ack      Ack
+	queuedAt time.Time
+	seq      uint64
+}
+
+// Ack is told the outcome of a queued message: Commit after the channel
+// accepted it, Release when it was not delivered.
+type Ack interface {
+	Commit(ctx context.Context) error
+	Release(ctx context.Context) error
+}
+
+type outboxHeap []outboxItem
+
+func (h outboxHeap) Len() int { return len(h) }
+func (h outboxHeap) Less(i, j int) bool {
+	if h[i].msg.Score != h[j].msg.Score {
+		return h[i].msg.Score > h[j].msg.Score
+	}
+	return h[i].seq < h[j].seq
+}
+func (h outboxHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *outboxHeap) Push(x any)   { *h = append(*h, x.(outboxItem)) }
+func (h *outboxHeap) Pop() any {
+	old := *h
+	n := len(old)
+	it := old[n-1]
+	*h = old[:n-1]
+	return it
+}
+
+// Outbox holds messages waiting for a channel's rate limit, highest score
+// first. A single dispatcher drains it; items that wait longer than maxAge
+// or overflow maxSize are handed to the expire callback as soon as they
+// enqueue or time out, without waiting for a rate token.
+type Outbox struct {
+	pusher  Pusher
+	rate    *RateLimiter
+	maxAge  time.Duration
+	maxSize int
+	logger  *logging.Logger
+
+	mu     sync.Mutex
+	items  outboxHeap
+	seq    uint64
+	notify chan struct{}
+	ctx    context.Context
+	expire ExpireFunc
+}
+
+type ExpireFunc func(ctx context.Context, msg model.ScoredMessage, reason string)
+
+func NewOutbox(pusher Pusher, rate *RateLimiter, maxSize int, maxAge time.Duration, logger *logging.Logger) *Outbox {
+	return &Outbox{
+		pusher:  pusher,
+		rate:    rate,
+		maxAge:  maxAge,
+		maxSize: maxSize,
+		logger:  logger,
+		notify:  make(chan struct{}, 1),
+	}
+}
+
+// Enqueue queues msg. When the queue is then over maxSize the lowest-scored
+// item, possibly msg itself, is dropped right away.
+func (o *Outbox) Enqueue(msg model.ScoredMessage, ack Ack) {
+	o.mu.Lock()
+	o.seq++
+	heap.Push(&o.items, outboxItem{msg: msg, ack: ack, queuedAt: time.Now(), seq: o.seq})
+	overflow, expired := o.evict()
+	ctx, expire := o.ctx, o.expire
+	o.mu.Unlock()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	o.dropAll(ctx, overflow, expired, expire)
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (o *Outbox) Run(ctx context.Context, expire ExpireFunc) {
+	defer o.releaseAll(ctx)
+	o.mu.Lock()
+	o.ctx, o.expire = ctx, expire
+	o.mu.Unlock()
+	if o.maxAge > 0 {
+		go o.expireLoop(ctx, expire)
+	}
+	for {
+		o.mu.Lock()
+		empty := o.items.Len() == 0
+		o.mu.Unlock()
+		if empty {
+			select {
+			case <-ctx.Done():
+				return
+			case <-o.notify:
+			}
+			continue
+		}
+		if err := o.rate.Wait(ctx); err != nil {
+			return
+		}
+		item, ok := o.next(ctx, expire)
+		if !ok {
+			o.rate.Release()
+			continue
+		}
+		o.send(ctx, item)
+	}
+}
+
+// expireLoop drops timed-out items while the dispatcher waits for a token.
+func (o *Outbox) expireLoop(ctx context.Context, expire ExpireFunc) {
+	interval := o.maxAge / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		o.mu.Lock()
+		overflow, expired := o.evict()
+		o.mu.Unlock()
+		o.dropAll(ctx, overflow, expired, expire)
+	}
+}
+
+func (o *Outbox) next(ctx context.Context, expire ExpireFunc) (outboxItem, bool) {
+	o.mu.Lock()
+	overflow, expired := o.evict()
+	var picked outboxItem
+	found := o.items.Len() > 0
+	if found {
+		picked = heap.Pop(&o.items).(outboxItem)
+	}
+	metrics.Set(metrics.Name("outbox_size", o.pusher.Name()), int64(o.items.Len()))
+	o.mu.Unlock()
+
+	o.dropAll(ctx, overflow, expired, expire)
+	return picked, found
+}
+
+// evict removes items past maxAge and, beyond maxSize, the lowest-scored
+// ones. The caller holds o.mu.
+func (o *Outbox) evict() (overflow, expired []outboxItem) {
+	if o.maxAge > 0 {
+		kept := o.items[:0]
+		for _, it := range o.items {
+			if time.Since(it.queuedAt) > o.maxAge {
+				expired = append(expired, it)
+			} else {
+				kept = append(kept, it)
+			}
+		}
+		if len(expired) > 0 {
+			o.items = kept
+			heap.Init(&o.items)
+		}
+	}
+	for o.maxSize > 0 && o.items.Len() > o.maxSize {
+		overflow = append(overflow, o.removeLowest())
+	}
+	metrics.Set(metrics.Name("outbox_size", o.pusher.Name()), int64(o.items.Len()))
+	return overflow, expired
+}
+
+func (o *Outbox) dropAll(ctx context.Context, overflow, expired []outboxItem, expire ExpireFunc) {
+	for _, it := range overflow {
+		o.drop(ctx, it, "overflow", expire)
+	}
+	for _, it := range expired {
+		o.drop(ctx, it, "expired", expire)
+	}
+}
+
+func (o *Outbox) removeLowest() outboxItem {
+	low := 0
+	for i := range o.items {
+		if o.items.Less(low, i) {
+			low = i
+		}
+	}
+	return heap.Remove(&o.items, low).(outboxItem)
+}
+
+func (o *Outbox) drop(ctx context.Context, it outboxItem, reason string, expire ExpireFunc) {
+	metrics.Inc(metrics.Name("outbox_"+reason, o.pusher.Name()))
+	o.logger.Warn("outbox "+reason, logging.Field{Key: "channel", Val: o.pusher.Name()}, logging.Field{Key: "source", Val: it.msg.Source}, logging.Field{Key: "score", Val: it.msg.Score}, logging.Field{Key: "waited_ms", Val: time.Since(it.queuedAt).Milliseconds()})
+	o.release(ctx, it)
+	if expire != nil {
+		expire(ctx, it.msg, reason)
+	}
+}
+
+func (o *Outbox) send(ctx context.Context, it outboxItem) {
+	content := o.pusher.Render(it.msg)
+	o.logger.Info("push payload", logging.Field{Key: "source", Val: it.msg.Source}, logging.Field{Key: "channel", Val: o.pusher.Name()}, logging.Field{Key: "len", Val: len(content)}, logging.Field{Key: "preview", Val: truncate(content, 200)})
+	if err := o.pusher.Send(ctx, it.msg, content); err != nil {
+		metrics.Inc(metrics.Name("push_failed", o.pusher.Name()))
+		o.logger.Error("push failed", logging.Field{Key: "source", Val: it.msg.Source}, logging.Field{Key: "channel", Val: o.pusher.Name()}, logging.Field{Key: "err", Val: err})
+		o.release(ctx, it)
+		return
+	}
+	if it.ack != nil {
+		if err := it.ack.Commit(context.WithoutCancel(ctx)); err != nil {
+			o.logger.Error("dedupe commit failed", logging.Field{Key: "source", Val: it.msg.Source}, logging.Field{Key: "channel", Val: o.pusher.Name()}, logging.Field{Key: "err", Val: err})
+		}
+	}
+	metrics.Inc(metrics.Name("push_sent", o.pusher.Name()))
+	o.logger.Info("pushed", logging.Field{Key: "source", Val: it.msg.Source}, logging.Field{Key: "channel", Val: o.pusher.Name()}, logging.Field{Key: "score", Val: it.msg.Score}, logging.Field{Key: "waited_ms", Val: time.Since(it.queuedAt).Milliseconds()})
+}
+
+// Handover moves every queued message to dst, keeping scores, queue order
+// and waiting time, so a reload does not drop pending pushes.
+func (o *Outbox) Handover(dst *Outbox) {
+	o.mu.Lock()
+	items := o.items
+	o.items = nil
+	metrics.Set(metrics.Name("outbox_size", o.pusher.Name()), 0)
+	o.mu.Unlock()
+	if len(items) == 0 {
+		return
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].seq < items[j].seq })
+	dst.mu.Lock()
+	for _, it := range items {
+		dst.seq++
+		it.seq = dst.seq
+		heap.Push(&dst.items, it)
+	}
+	metrics.Set(metrics.Name("outbox_size", dst.pusher.Name()), int64(dst.items.Len()))
+	dst.mu.Unlock()
+	select {
+	case dst.notify <- struct{}{}:
+	default:
+	}
+	o.logger.Info("outbox handed over", logging.Field{Key: "channel", Val: o.pusher.Name()}, logging.Field{Key: "count", Val: len(items)})
+}
+
+func (o *Outbox) releaseAll(ctx context.Context) {
+	o.mu.Lock()
+	items := o.items
+	o.items = nil
+	o.mu.Unlock()
+	for _, it := range items {
+		o.release(ctx, it)
+	}
+}
+
+func (o *Outbox) release(ctx context.Context, it outboxItem) {
+	if it.ack == nil {
+		return
+	}
+	if err := it.ack.Release(context.WithoutCancel(ctx)); err != nil {
+		o.logger.Error("dedupe release failed", logging.Field{Key: "source", Val: it.msg.Source}, logging.Field{Key: "channel", Val: o.pusher.Name()}, logging.Field{Key: "err", Val: err})
+	}
+}
+
+func truncate(s string, max int) string {
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+	return s[:max] + "..."
+}
@@ -0,0 +1,125 @@
+package push
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"realtime-message/internal/logging"
+	"realtime-message/internal/model"
+)
+
+type recordPusher struct{ sent []string }
+
+func (p *recordPusher) Name() string                          { return "test" }
+func (p *recordPusher) Render(msg model.ScoredMessage) string { return msg.Title }
+func (p *recordPusher) Send(ctx context.Context, msg model.ScoredMessage, body string) error {
+	p.sent = append(p.sent, body)
+	return nil
+}
+
+type recordAck struct{ committed, released bool }
+
+func (a *recordAck) Commit(ctx context.Context) error  { a.committed = true; return nil }
+func (a *recordAck) Release(ctx context.Context) error { a.released = true; return nil }
+
+func scored(title string, score int) model.ScoredMessage {
+	return model.ScoredMessage{Message: model.Message{Title: title}, Score: score}
+}
+
+// drain sends everything still queued, in dispatch order.
+func drain(o *Outbox) {
+	for {
+		it, ok := o.next(context.Background(), o.expire)
+		if !ok {
+			return
+		}
+		o.send(context.Background(), it)
+	}
+}
+
+func TestOutboxOrder(t *testing.T) {
+	cases := []struct {
+		name    string
+		maxSize int
+		scores  []int
+		want    []string
+	}{
+		{"by score then arrival", 0, []int{1, 5, 3, 5}, []string{"b", "d", "c", "a"}},
+		{"overflow drops lowest", 2, []int{1, 5, 3}, []string{"b", "c"}},
+		{"overflow drops newcomer", 2, []int{5, 3, 1}, []string{"a", "b"}},
+		{"overflow keeps earlier tie", 2, []int{4, 4, 4}, []string{"a", "b"}},
+	}
+	for _, c := range cases {
+		p := &recordPusher{}
+		o := NewOutbox(p, NewRateLimiter(0), c.maxSize, 0, logging.New(false))
+		var dropped []string
+		o.expire = func(ctx context.Context, msg model.ScoredMessage, reason string) {
+			dropped = append(dropped, msg.Title+":"+reason)
+		}
+		for i, s := range c.scores {
+			o.Enqueue(scored(string(rune('a'+i)), s), nil)
+		}
+		if c.maxSize > 0 && o.items.Len() > c.maxSize {
+			t.Errorf("%s: %d queued over max %d", c.name, o.items.Len(), c.maxSize)
+		}
+		if len(c.scores)-len(dropped) != len(c.want) {
+			t.Errorf("%s: dropped %v", c.name, dropped)
+		}
+		drain(o)
+		if len(p.sent) != len(c.want) {
+			t.Fatalf("%s: sent %v, want %v", c.name, p.sent, c.want)
+		}
+		for i := range c.want {
+			if p.sent[i] != c.want[i] {
+				t.Errorf("%s: sent %v, want %v", c.name, p.sent, c.want)
+				break
+			}
+		}
+	}
+}
+
+func TestOutboxExpire(t *testing.T) {
+	p := &recordPusher{}
+	o := NewOutbox(p, NewRateLimiter(0), 0, time.Minute, logging.New(false))
+	var dropped []string
+	o.expire = func(ctx context.Context, msg model.ScoredMessage, reason string) {
+		dropped = append(dropped, msg.Title+":"+reason)
+	}
+	old, fresh := &recordAck{}, &recordAck{}
+	o.Enqueue(scored("old", 9), old)
+	o.Enqueue(scored("fresh", 1), fresh)
+	o.items[0].queuedAt = time.Now().Add(-2 * time.Minute)
+	if o.items[0].msg.Title != "old" {
+		t.Fatalf("heap top is %s", o.items[0].msg.Title)
+	}
+	drain(o)
+	if len(dropped) != 1 || dropped[0] != "old:expired" || !old.released || old.committed {
+		t.Errorf("dropped %v, old ack %+v", dropped, *old)
+	}
+	if len(p.sent) != 1 || p.sent[0] != "fresh" || !fresh.committed {
+		t.Errorf("sent %v, fresh ack %+v", p.sent, *fresh)
+	}
+}
+
+func TestOutboxHandover(t *testing.T) {
+	src := NewOutbox(&recordPusher{}, NewRateLimiter(0), 0, 0, logging.New(false))
+	p := &recordPusher{}
+	dst := NewOutbox(p, NewRateLimiter(0), 0, 0, logging.New(false))
+	dst.Enqueue(scored("d1", 3), nil)
+	for i, s := range []int{3, 5, 3} {
+		src.Enqueue(scored(string(rune('a'+i)), s), nil)
+	}
+	queued := src.items[0].queuedAt
+	src.Handover(dst)
+	if src.items.Len() != 0 || dst.items.Len() != 4 || !dst.items[0].queuedAt.Equal(queued) {
+		t.Fatalf("src %d, dst %d", src.items.Len(), dst.items.Len())
+	}
+	drain(dst)
+	want := []string{"b", "d1", "a", "c"}
+	for i := range want {
+		if i >= len(p.sent) || p.sent[i] != want[i] {
+			t.Fatalf("sent %v, want %v", p.sent, want)
+		}
+	}
+}
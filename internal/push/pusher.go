@@ -0,0 +1,140 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"realtime-message/internal/config"
+	"realtime-message/internal/model"
+)
+
+type Pusher interface {
+	Name() string
+	Render(msg model.ScoredMessage) string
+	Send(ctx context.Context, msg model.ScoredMessage, body string) error
+}
+
+func New(cfg config.ChannelConfig) (Pusher, error) {
+	timeout := time.Duration(cfg.TimeoutMS) * time.Millisecond
+	if timeout <= 0 {
+		timeout = 8 * time.Second
+	}
+	switch strings.ToLower(cfg.Type) {
+	case "dingtalk":
+		return &DingTalk{
+			Channel:  cfg.Name,
+			Webhook:  cfg.Webhook,
+			Secret:   cfg.Secret,
+			MsgType:  cfg.MsgType,
+			Title:    cfg.Title,
+			Timeout:  timeout,
+			Template: cfg.Template,
+		}, nil
+	case "wecom":
+		return &WeCom{
+			Channel:  cfg.Name,
+			Webhook:  cfg.Webhook,
+			MsgType:  cfg.MsgType,
+			Timeout:  timeout,
+			Template: cfg.Template,
+		}, nil
+	case "feishu":
+		return &Feishu{
+			Channel:  cfg.Name,
+			Webhook:  cfg.Webhook,
+			Secret:   cfg.Secret,
+			MsgType:  cfg.MsgType,
+			Title:    cfg.Title,
+			Timeout:  timeout,
+			Template: cfg.Template,
+		}, nil
+	case "webhook":
+		return &Webhook{
+			Channel:  cfg.Name,
+			URL:      cfg.Webhook,
+			Secret:   cfg.Secret,
+			Headers:  cfg.Headers,
+			Timeout:  timeout,
+			Template: cfg.Template,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported channel type %q", cfg.Type)
+	}
+}
+
+func NewAll(channels []config.ChannelConfig) ([]Pusher, error) {
+	pushers := make([]Pusher, 0, len(channels))
+	for _, ch := range channels {
+		p, err := New(ch)
+		if err != nil {
+			return nil, fmt.Errorf("channel %s: %w", ch.Name, err)
+		}
+		pushers = append(pushers, p)
+	}
+	return pushers, nil
+}
+
+func Render(tpl string, msg model.ScoredMessage) string {
+	if msg.Title == "" && msg.Content != "" {
+		msg.Title = msg.Content
+	}
+	if msg.Content == "" && msg.Title != "" {
+		msg.Content = msg.Title
+	}
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+	if msg.Title == "" && msg.Content == "" {
+		msg.Title = "(no title)"
+	}
+	fallback := fmt.Sprintf("[%s] %s\n%s\n%s", msg.Source, msg.Title, msg.Content, msg.URL)
+	if tpl == "" {
+		return fallback
+	}
+	values := map[string]string{
+		"source":  msg.Source,
+		"title":   msg.Title,
+		"content": msg.Content,
+		"time":    msg.Time.Format("2006-01-02 15:04:05"),
+		"score":   fmt.Sprintf("%d", msg.Score),
+		"reasons": strings.Join(msg.Reasons, ","),
+		"link":    msg.URL,
+	}
+	rendered := RenderTemplate(tpl, values)
+	if strings.TrimSpace(rendered) == "" {
+		return fallback
+	}
+	return rendered
+}
+
+func postJSON(ctx context.Context, timeout time.Duration, endpoint string, headers map[string]string, payload any) (int, []byte, error) {
+	buf, err := json.Marshal(payload)
+	if err != nil {
+		return 0, nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(buf))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, err
+	}
+	return resp.StatusCode, body, nil
+}
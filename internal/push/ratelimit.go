@@ -1,9 +1,15 @@
 package push
 
-import "time"
+import (
+	"context"
+	"sync"
+	"time"
+)
 
 type RateLimiter struct {
-	ch chan struct{}
+	ch   chan struct{}
+	stop chan struct{}
+	once sync.Once
 }
 
 func NewRateLimiter(maxPerMinute int) *RateLimiter {
@@ -14,11 +20,16 @@ func NewRateLimiter(maxPerMinute int) *RateLimiter {
 	for i := 0; i < maxPerMinute; i++ {
 		ch <- struct{}{}
 	}
-	rl := &RateLimiter{ch: ch}
+	rl := &RateLimiter{ch: ch, stop: make(chan struct{})}
 	go func() {
 		ticker := time.NewTicker(time.Minute)
 		defer ticker.Stop()
-		for range ticker.C {
+		for {
+			select {
+			case <-rl.stop:
+				return
+			case <-ticker.C:
+			}
 			for i := 0; i < maxPerMinute; i++ {
 				select {
 				case rl.ch <- struct{}{}:
@@ -31,14 +42,32 @@ func NewRateLimiter(maxPerMinute int) *RateLimiter {
 	return rl
 }
 
-func (r *RateLimiter) Allow() bool {
+// Stop ends the refill goroutine. Tokens already available can still be used.
+func (r *RateLimiter) Stop() {
+	if r.stop == nil {
+		return
+	}
+	r.once.Do(func() { close(r.stop) })
+}
+
+func (r *RateLimiter) Wait(ctx context.Context) error {
 	if r.ch == nil {
-		return true
+		return ctx.Err()
 	}
 	select {
 	case <-r.ch:
-		return true
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *RateLimiter) Release() {
+	if r.ch == nil {
+		return
+	}
+	select {
+	case r.ch <- struct{}{}:
 	default:
-		return false
 	}
 }
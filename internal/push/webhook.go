@@ -0,0 +1,78 @@
+package push
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"realtime-message/internal/model"
+)
+
+type Webhook struct {
+	Channel  string
+	URL      string
+	Secret   string
+	Headers  map[string]string
+	Timeout  time.Duration
+	Template string
+}
+
+type webhookPayload struct {
+	Channel string    `json:"channel"`
+	Source  string    `json:"source"`
+	ID      string    `json:"id,omitempty"`
+	Title   string    `json:"title"`
+	Content string    `json:"content"`
+	URL     string    `json:"url,omitempty"`
+	Time    time.Time `json:"time"`
+	Score   int       `json:"score"`
+	Reasons []string  `json:"reasons"`
+	Text    string    `json:"text"`
+}
+
+func (w *Webhook) Name() string {
+	return w.Channel
+}
+
+func (w *Webhook) Render(msg model.ScoredMessage) string {
+	return Render(w.Template, msg)
+}
+
+func (w *Webhook) Send(ctx context.Context, msg model.ScoredMessage, body string) error {
+	buf, err := json.Marshal(webhookPayload{
+		Channel: w.Channel,
+		Source:  msg.Source,
+		ID:      msg.ID,
+		Title:   msg.Title,
+		Content: msg.Content,
+		URL:     msg.URL,
+		Time:    msg.Time,
+		Score:   msg.Score,
+		Reasons: msg.Reasons,
+		Text:    body,
+	})
+	if err != nil {
+		return err
+	}
+	headers := map[string]string{}
+	for k, v := range w.Headers {
+		headers[k] = v
+	}
+	if w.Secret != "" {
+		h := hmac.New(sha256.New, []byte(w.Secret))
+		_, _ = h.Write(buf)
+		headers["X-Signature"] = "sha256=" + hex.EncodeToString(h.Sum(nil))
+	}
+	status, _, err := postJSON(ctx, w.Timeout, w.URL, headers, json.RawMessage(buf))
+	if err != nil {
+		return err
+	}
+	if status < 200 || status >= 300 {
+		return fmt.Errorf("webhook status %d", status)
+	}
+	return nil
+}
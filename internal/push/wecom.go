@@ -0,0 +1,59 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"realtime-message/internal/model"
+)
+
+type WeCom struct {
+	Channel  string
+	Webhook  string
+	MsgType  string
+	Timeout  time.Duration
+	Template string
+}
+
+func (w *WeCom) Name() string {
+	return w.Channel
+}
+
+func (w *WeCom) Render(msg model.ScoredMessage) string {
+	return Render(w.Template, msg)
+}
+
+func (w *WeCom) Send(ctx context.Context, msg model.ScoredMessage, body string) error {
+	payload := map[string]any{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"content": body,
+		},
+	}
+	if strings.ToLower(w.MsgType) == "text" {
+		payload = map[string]any{
+			"msgtype": "text",
+			"text": map[string]string{
+				"content": body,
+			},
+		}
+	}
+	status, resp, err := postJSON(ctx, w.Timeout, w.Webhook, nil, payload)
+	if err != nil {
+		return err
+	}
+	if status < 200 || status >= 300 {
+		return fmt.Errorf("wecom status %d", status)
+	}
+	var r Response
+	if err := json.Unmarshal(resp, &r); err != nil {
+		return fmt.Errorf("wecom response: %w", err)
+	}
+	if r.ErrCode != 0 {
+		return fmt.Errorf("wecom error %d: %s", r.ErrCode, r.ErrMsg)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package rdb
+
+import (
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+
+	"realtime-message/internal/config"
+)
+
+var (
+	mu      sync.Mutex
+	clients = map[config.RedisConfig]*redis.Client{}
+)
+
+// Client returns the shared client for cfg. Reloads that keep the same
+// Redis settings reuse its connection pool instead of opening a new one.
+func Client(cfg config.RedisConfig) *redis.Client {
+	key := config.RedisConfig{Addr: cfg.Addr, Password: cfg.Password, DB: cfg.DB}
+	mu.Lock()
+	defer mu.Unlock()
+	if c, ok := clients[key]; ok {
+		return c
+	}
+	c := redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+	clients[key] = c
+	return c
+}
@@ -0,0 +1,167 @@
+package route
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"realtime-message/internal/config"
+	"realtime-message/internal/logging"
+	"realtime-message/internal/model"
+	"realtime-message/internal/push"
+)
+
+type Target struct {
+	Pusher    push.Pusher
+	Threshold int
+	Outbox    *push.Outbox
+	rate      *push.RateLimiter
+}
+
+type Router struct {
+	rules   []config.RouteConfig
+	targets map[string]*Target
+	order   []string
+}
+
+func New(channels []config.ChannelConfig, routes []config.RouteConfig, queue config.QueueConfig, pushers []push.Pusher, logger *logging.Logger) *Router {
+	r := &Router{rules: routes, targets: map[string]*Target{}}
+	maxAge := time.Duration(queue.MaxAgeSeconds) * time.Second
+	if maxAge <= 0 {
+		maxAge = 5 * time.Minute
+	}
+	byName := map[string]push.Pusher{}
+	for _, p := range pushers {
+		byName[p.Name()] = p
+	}
+	for _, ch := range channels {
+		p, ok := byName[ch.Name]
+		if !ok {
+			continue
+		}
+		rate := push.NewRateLimiter(ch.MaxPushPerMinute)
+		r.targets[ch.Name] = &Target{
+			Pusher:    p,
+			Threshold: ch.PushThreshold,
+			Outbox:    push.NewOutbox(p, rate, queue.MaxSize, maxAge, logger),
+			rate:      rate,
+		}
+		r.order = append(r.order, ch.Name)
+	}
+	return r
+}
+
+func (r *Router) Run(ctx context.Context, expire push.ExpireFunc) {
+	for _, name := range r.order {
+		go r.targets[name].Outbox.Run(ctx, expire)
+	}
+}
+
+// Handover moves the queued pushes of each channel to the channel of the
+// same name in next. Channels that are gone keep theirs, which are released
+// when their outbox stops.
+func (r *Router) Handover(next *Router) {
+	for name, t := range r.targets {
+		if nt, ok := next.targets[name]; ok {
+			t.Outbox.Handover(nt.Outbox)
+		}
+	}
+}
+
+// Limiters returns each channel's push rate limiter by name.
+func (r *Router) Limiters() map[string]*push.RateLimiter {
+	out := make(map[string]*push.RateLimiter, len(r.targets))
+	for name, t := range r.targets {
+		out[name] = t.rate
+	}
+	return out
+}
+
+// Stop ends the rate limiters of a router that is being replaced.
+func (r *Router) Stop() {
+	for _, t := range r.targets {
+		t.rate.Stop()
+	}
+}
+
+// Channels returns the channel names in declaration order.
+func (r *Router) Channels() []string {
+	return append([]string(nil), r.order...)
+}
+
+// Route returns the targets a message fans out to, in channel declaration
+// order. Without any routes every channel is a candidate. Targets whose
+// threshold is above the message score are left out.
+func (r *Router) Route(msg model.ScoredMessage) []*Target {
+	selected := map[string]bool{}
+	if len(r.rules) == 0 {
+		for _, name := range r.order {
+			selected[name] = true
+		}
+	}
+	for _, rule := range r.rules {
+		if !matches(rule, msg) {
+			continue
+		}
+		for _, name := range rule.Channels {
+			selected[name] = true
+		}
+	}
+	var out []*Target
+	for _, name := range r.order {
+		t := r.targets[name]
+		if selected[name] && msg.Score >= t.Threshold {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func matches(rule config.RouteConfig, msg model.ScoredMessage) bool {
+	if len(rule.Topics) > 0 && !hasAnyReason(msg, rule.Topics) {
+		return false
+	}
+	if len(rule.Sources) > 0 && !containsAny([]string{msg.Source}, rule.Sources) {
+		return false
+	}
+	if msg.Score < rule.MinScore {
+		return false
+	}
+	if rule.MaxScore > 0 && msg.Score > rule.MaxScore {
+		return false
+	}
+	if len(rule.Keywords) > 0 {
+		text := strings.ToLower(msg.Title + " " + msg.Content)
+		hit := false
+		for _, k := range rule.Keywords {
+			if k != "" && strings.Contains(text, strings.ToLower(k)) {
+				hit = true
+				break
+			}
+		}
+		if !hit {
+			return false
+		}
+	}
+	return true
+}
+
+func hasAnyReason(msg model.ScoredMessage, names []string) bool {
+	for _, name := range names {
+		if msg.HasReason(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(values, wanted []string) bool {
+	for _, v := range values {
+		for _, w := range wanted {
+			if v == w {
+				return true
+			}
+		}
+	}
+	return false
+}
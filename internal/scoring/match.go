@@ -0,0 +1,97 @@
+package scoring
+
+import (
+	"regexp"
+	"strings"
+
+	"realtime-message/internal/config"
+)
+
+type matcher struct {
+	allOf  []keyword
+	anyOf  []keyword
+	noneOf []keyword
+	regex  []*regexp.Regexp
+}
+
+type keyword struct {
+	word  string
+	lower string
+}
+
+func newMatcher(r config.KeywordRules) (matcher, error) {
+	m := matcher{
+		allOf:  keywords(r.AllOf),
+		anyOf:  keywords(append(append([]string{}, r.Keywords...), r.AnyOf...)),
+		noneOf: keywords(r.NoneOf),
+	}
+	for _, expr := range r.Regex {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return matcher{}, err
+		}
+		m.regex = append(m.regex, re)
+	}
+	return m, nil
+}
+
+// match returns the terms that made the text match, or false. Keywords are
+// compared against lower, regexes against the original raw text.
+func (m matcher) match(lower, raw string) ([]string, bool) {
+	if len(m.allOf) == 0 && len(m.anyOf) == 0 && len(m.regex) == 0 {
+		return nil, false
+	}
+	var terms []string
+	for _, k := range m.allOf {
+		if !strings.Contains(lower, k.lower) {
+			return nil, false
+		}
+		terms = append(terms, k.word)
+	}
+	if len(m.anyOf) > 0 || len(m.regex) > 0 {
+		hit := false
+		for _, k := range m.anyOf {
+			if strings.Contains(lower, k.lower) {
+				terms = append(terms, k.word)
+				hit = true
+			}
+		}
+		for _, re := range m.regex {
+			if found := re.FindString(raw); found != "" {
+				terms = append(terms, found)
+				hit = true
+			}
+		}
+		if !hit {
+			return nil, false
+		}
+	}
+	for _, k := range m.noneOf {
+		if strings.Contains(lower, k.lower) {
+			return nil, false
+		}
+	}
+	return terms, true
+}
+
+func keywords(words []string) []keyword {
+	out := make([]keyword, 0, len(words))
+	for _, w := range words {
+		if w == "" {
+			continue
+		}
+		out = append(out, keyword{word: w, lower: strings.ToLower(w)})
+	}
+	return out
+}
+
+func lowerAll(words []string) []string {
+	out := make([]string, 0, len(words))
+	for _, w := range words {
+		if w == "" {
+			continue
+		}
+		out = append(out, strings.ToLower(w))
+	}
+	return out
+}
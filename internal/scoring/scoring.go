@@ -1,9 +1,10 @@
 package scoring
 
 import (
+	"fmt"
 	"strings"
-	"time"
 
+	"realtime-message/internal/calendar"
 	"realtime-message/internal/config"
 	"realtime-message/internal/model"
 )
@@ -12,40 +13,112 @@ type Engine struct {
 	Topics   []config.TopicConfig
 	Triggers config.TriggerConfig
 	Scoring  config.ScoringConfig
+
+	topicMatchers []matcher
+	strong        matcher
+	blockWords    []string
+	blockSources  map[string]bool
+	calendar      *calendar.Calendar
+}
+
+func NewEngine(topics []config.TopicConfig, triggers config.TriggerConfig, scoring config.ScoringConfig, cal *calendar.Calendar) (*Engine, error) {
+	e := &Engine{Topics: topics, Triggers: triggers, Scoring: scoring, blockSources: map[string]bool{}, calendar: cal}
+	for _, t := range topics {
+		m, err := newMatcher(t.KeywordRules)
+		if err != nil {
+			return nil, fmt.Errorf("topic %s: %w", t.Name, err)
+		}
+		e.topicMatchers = append(e.topicMatchers, m)
+	}
+	strong, err := newMatcher(triggers.Strong.KeywordRules)
+	if err != nil {
+		return nil, fmt.Errorf("strong trigger: %w", err)
+	}
+	e.strong = strong
+	e.blockWords = lowerAll(scoring.Blacklist.Words)
+	for _, s := range scoring.Blacklist.Sources {
+		e.blockSources[s] = true
+	}
+	return e, nil
+}
+
+// Component is one contribution to a message's score.
+type Component struct {
+	Name   string
+	Points int
 }
 
 func (e *Engine) Score(msg model.Message) model.ScoredMessage {
-	text := strings.ToLower(msg.Title + " " + msg.Content)
+	scored, _ := e.Explain(msg)
+	return scored
+}
+
+// Explain scores msg and also returns every component that contributed.
+func (e *Engine) Explain(msg model.Message) (model.ScoredMessage, []Component) {
+	raw := msg.Title + " " + msg.Content
+	text := strings.ToLower(raw)
+	if e.blockSources[msg.Source] {
+		return blocked(msg, msg.Source)
+	}
+	for _, w := range e.blockWords {
+		if strings.Contains(text, w) {
+			return blocked(msg, w)
+		}
+	}
+
 	score := 0
 	reasons := []string{}
+	components := []Component{}
+	add := func(name string, points int) {
+		score += points
+		reasons = append(reasons, name)
+		components = append(components, Component{Name: name, Points: points})
+	}
 
-	score += msgBase(msg)
-	if msgBase(msg) != 0 {
-		reasons = append(reasons, "base")
+	if base := msgBase(msg); base != 0 {
+		add("base", base)
 	}
 
-	for _, t := range e.Topics {
-		if hitAny(text, t.Keywords) {
-			score += t.Weight
-			reasons = append(reasons, t.Name)
+	for i, t := range e.Topics {
+		if terms, ok := e.topicMatchers[i].match(text, raw); ok {
+			add(reason(t.Name, terms), t.Weight)
 		}
 	}
-	if hitAny(text, e.Triggers.Strong.Keywords) {
-		score += e.Triggers.Strong.Weight
-		reasons = append(reasons, "strong")
+	if terms, ok := e.strong.match(text, raw); ok {
+		add(reason("strong", terms), e.Triggers.Strong.Weight)
 	}
 
-	if e.Scoring.MarketHours.Enabled {
-		if inMarketHours(msg.Time) {
-			score += e.Scoring.MarketHours.InSessionBonus
-			reasons = append(reasons, "in_session")
+	if e.Scoring.MarketHours.Enabled && e.calendar != nil && !msg.TimeUnknown {
+		phase := e.calendar.Phase(msg.Time)
+		if phases := e.Scoring.MarketHours.Phases; len(phases) > 0 {
+			if points, ok := phases[phase]; ok {
+				add(phase, points)
+			}
+		} else if phase == calendar.InSession {
+			add("in_session", e.Scoring.MarketHours.InSessionBonus)
 		} else {
-			score -= e.Scoring.MarketHours.OffSessionPenalty
-			reasons = append(reasons, "off_session")
+			add("off_session", -e.Scoring.MarketHours.OffSessionPenalty)
 		}
 	}
 
-	return model.ScoredMessage{Message: msg, Score: score, Reasons: reasons}
+	return model.ScoredMessage{Message: msg, Score: score, Reasons: reasons}, components
+}
+
+// Blocked reports whether scored was zeroed by the blacklist.
+func Blocked(scored model.ScoredMessage) bool {
+	return len(scored.Reasons) == 1 && strings.HasPrefix(scored.Reasons[0], "blacklist:")
+}
+
+func blocked(msg model.Message, term string) (model.ScoredMessage, []Component) {
+	name := "blacklist:" + term
+	return model.ScoredMessage{Message: msg, Score: 0, Reasons: []string{name}}, []Component{{Name: name, Points: 0}}
+}
+
+func reason(name string, terms []string) string {
+	if len(terms) == 0 {
+		return name
+	}
+	return name + ":" + strings.Join(terms, "/")
 }
 
 func msgBase(msg model.Message) int {
@@ -57,29 +130,3 @@ var msgBaseScore = map[string]int{}
 func SetBaseScores(scores map[string]int) {
 	msgBaseScore = scores
 }
-
-func hitAny(text string, keywords []string) bool {
-	for _, k := range keywords {
-		if k == "" {
-			continue
-		}
-		if strings.Contains(text, strings.ToLower(k)) {
-			return true
-		}
-	}
-	return false
-}
-
-func inMarketHours(t time.Time) bool {
-	lt := t.In(time.Local)
-	h := lt.Hour()
-	m := lt.Minute()
-	// Default A-share session: 09:30-11:30 and 13:00-15:00
-	if (h == 9 && m >= 30) || (h > 9 && h < 11) || (h == 11 && m <= 30) {
-		return true
-	}
-	if (h == 13) || (h == 14) || (h == 15 && m == 0) {
-		return true
-	}
-	return false
-}
@@ -0,0 +1,83 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"realtime-message/internal/config"
+	"realtime-message/internal/model"
+	"realtime-message/internal/rdb"
+)
+
+// Decisions recorded with every published message.
+const (
+	Queued    = "queued"
+	Duplicate = "duplicate"
+	Digest    = "digest"
+	Held      = "held"
+	Drop      = "drop"
+)
+
+// Record is the JSON stored in the "data" field of each stream entry.
+type Record struct {
+	ID       string    `json:"id"`
+	Title    string    `json:"title"`
+	Content  string    `json:"content"`
+	URL      string    `json:"url"`
+	Time     time.Time `json:"time"`
+	Source   string    `json:"source"`
+	Score    int       `json:"score"`
+	Reasons  []string  `json:"reasons"`
+	Decision string    `json:"decision"`
+	Channels []string  `json:"channels,omitempty"`
+	// TimeUnknown is set when the source time could not be parsed.
+	TimeUnknown bool `json:"time_unknown,omitempty"`
+}
+
+// Sink appends every scored message to a Redis stream for downstream tools.
+type Sink struct {
+	client *redis.Client
+	stream string
+	maxLen int64
+}
+
+func New(rcfg config.RedisConfig, cfg config.SinkConfig) *Sink {
+	client := rdb.Client(rcfg)
+	stream := cfg.Stream
+	if stream == "" {
+		stream = rcfg.KeyPrefix + "scored"
+	}
+	maxLen := cfg.MaxLen
+	if maxLen <= 0 {
+		maxLen = 100000
+	}
+	return &Sink{client: client, stream: stream, maxLen: maxLen}
+}
+
+func (s *Sink) Publish(ctx context.Context, msg model.ScoredMessage, decision string, channels []string) error {
+	raw, err := json.Marshal(Record{
+		ID:          msg.ID,
+		Title:       msg.Title,
+		Content:     msg.Content,
+		URL:         msg.URL,
+		Time:        msg.Time,
+		Source:      msg.Source,
+		Score:       msg.Score,
+		Reasons:     msg.Reasons,
+		Decision:    decision,
+		Channels:    channels,
+		TimeUnknown: msg.TimeUnknown,
+	})
+	if err != nil {
+		return err
+	}
+	return s.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.stream,
+		MaxLen: s.maxLen,
+		Approx: true,
+		Values: map[string]any{"data": raw, "source": msg.Source, "decision": decision, "score": msg.Score},
+	}).Err()
+}